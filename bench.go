@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// benchSample is the text translated by -bench
+const benchSample = "The quick brown fox jumps over the lazy dog."
+
+// benchBackend is a backend timed by bench
+type benchBackend struct {
+	name string
+	tr   translate.Translator
+}
+
+// bench translates benchSample into target n times in a row with every backend, and prints
+// the minimum, average, maximum and 95th percentile of the latency of the successful requests
+func bench(w io.Writer, backends []benchBackend, target string, n int) {
+	fmt.Fprintf(w, "%-10s %6s %6s %9s %9s %9s %9s\n", "backend", "runs", "failed", "min", "avg", "max", "p95")
+	for _, b := range backends {
+		var times []time.Duration
+		failed := 0
+		for i := 0; i < n; i++ {
+			start := time.Now()
+			if _, err := b.tr.Translate(target, benchSample); err != nil {
+				slog.Warn("unable to translate", "backend", b.name, "err", err)
+				failed++
+				continue
+			}
+			times = append(times, time.Since(start))
+		}
+		if len(times) == 0 {
+			fmt.Fprintf(w, "%-10s %6d %6d %9s %9s %9s %9s\n", b.name, n, failed, "-", "-", "-", "-")
+			continue
+		}
+		slices.Sort(times)
+		var total time.Duration
+		for _, t := range times {
+			total += t
+		}
+		// the nearest-rank percentile
+		p95 := times[(len(times)*95+99)/100-1]
+		ms := func(d time.Duration) string { return d.Round(time.Millisecond).String() }
+		fmt.Fprintf(w, "%-10s %6d %6d %9s %9s %9s %9s\n", b.name, n, failed,
+			ms(times[0]), ms(total/time.Duration(len(times))), ms(times[len(times)-1]), ms(p95))
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// snapCandidate limits the detected language of text to the -candidates: a detection outside of
+// them snaps to the only candidate written in the script of text, and is rejected otherwise
+func (a *app) snapCandidate(text, detected string) (string, bool) {
+	isDetected := func(c string) bool { return c == detected || strings.HasPrefix(detected, c+"-") }
+	if len(a.candidates) == 0 || slices.ContainsFunc(a.candidates, isDetected) {
+		return detected, true
+	}
+	var fits []string
+	for _, c := range a.candidates {
+		if translate.ScriptShare(text, c) >= translate.MinScriptShare {
+			fits = append(fits, c)
+		}
+	}
+	if len(fits) != 1 {
+		return detected, false
+	}
+	return fits[0], true
+}
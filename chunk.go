@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultChunkSize keeps requests well within the limits of the backends
+const defaultChunkSize = 5000
+
+// splitChunks splits text at paragraph boundaries into chunks of at most size bytes,
+// falling back to sentence boundaries for longer paragraphs; the chunks concatenated give back text
+func splitChunks(text string, size int) []string {
+	var pieces []string
+	for _, para := range strings.SplitAfter(text, "\n\n") {
+		if len(para) > size {
+			pieces = append(pieces, splitSentences(para)...)
+		} else {
+			pieces = append(pieces, para)
+		}
+	}
+
+	var chunks []string
+	var cur strings.Builder
+	for _, piece := range pieces {
+		if cur.Len() > 0 && cur.Len()+len(piece) > size {
+			chunks = append(chunks, cur.String())
+			cur.Reset()
+		}
+		cur.WriteString(piece)
+	}
+	if cur.Len() > 0 {
+		chunks = append(chunks, cur.String())
+	}
+	return chunks
+}
+
+// splitSentences splits text after every sentence terminator, keeping with each sentence
+// the whitespace that follows it; Latin terminators only count when followed by whitespace
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i, r := range text {
+		end := i + utf8.RuneLen(r)
+		if strings.ContainsRune(".!?", r) {
+			next, _ := utf8.DecodeRuneInString(text[end:])
+			if !unicode.IsSpace(next) {
+				continue
+			}
+		} else if !strings.ContainsRune("。！？", r) {
+			continue
+		}
+		end += len(text[end:]) - len(strings.TrimLeftFunc(text[end:], unicode.IsSpace))
+		if end > start && end < len(text) {
+			sentences = append(sentences, text[start:end])
+			start = end
+		}
+	}
+	return append(sentences, text[start:])
+}
+
+// detectionSample returns the part of text used to detect its language: the first non-empty chunk
+func (a *app) detectionSample(text string) string {
+	if a.chunkSize <= 0 || len(text) <= a.chunkSize {
+		return text
+	}
+	for _, chunk := range splitChunks(text, a.chunkSize) {
+		if strings.TrimSpace(chunk) != "" {
+			return chunk
+		}
+	}
+	return text
+}
+
+// translate translates text into target, splitting it in chunks if it is too long for a single request;
+// the whitespace around text, or around every chunk, is kept as it is
+func (a *app) translate(target, text string) (string, error) {
+	if a.chunkSize <= 0 || len(text) <= a.chunkSize {
+		return keepSpace(text, func(core string) (string, error) {
+			return a.translateSegment(target, core)
+		})
+	}
+	chunks := splitChunks(text, a.chunkSize)
+	results := make([]string, len(chunks))
+	g, req := a.group()
+	for i, chunk := range chunks {
+		if req.ctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			trans, err := keepSpace(chunk, func(core string) (string, error) {
+				return req.translateSegment(target, core)
+			})
+			results[i] = trans
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return "", err
+	}
+	return strings.Join(results, ""), nil
+}
+
+// group returns a group running up to -concurrency functions, and a copy of a translating with
+// the context of the group: once a function fails, or the request of a is canceled, the requests
+// in flight are aborted and the functions not started yet are skipped
+func (a *app) group() (*errgroup.Group, *app) {
+	parent := a.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	g, ctx := errgroup.WithContext(parent)
+	g.SetLimit(max(a.concurrency, 1))
+	return g, a.withContext(ctx)
+}
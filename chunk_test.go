@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+func TestSplitChunks(t *testing.T) {
+	tests := []struct {
+		text string
+		size int
+		want []string
+	}{
+		{"short", 10, []string{"short"}},
+		{
+			"first para.\n\nsecond para.\n\nthird para.\n",
+			30,
+			[]string{"first para.\n\nsecond para.\n\n", "third para.\n"},
+		},
+		{
+			"one.\n\ntwo.\n\nthree.",
+			6,
+			[]string{"one.\n\n", "two.\n\n", "three."},
+		},
+		{
+			"A long sentence. Another one! And a last one?\n\nnext",
+			20,
+			[]string{"A long sentence. ", "Another one! ", "And a last one?\n\n", "next"},
+		},
+		{"一句。二句。", 6, []string{"一句。", "二句。"}},
+		{"3.14 is not a boundary", 5, []string{"3.14 is not a boundary"}},
+	}
+	for _, tt := range tests {
+		got := splitChunks(tt.text, tt.size)
+		if strings.Join(got, "") != tt.text {
+			t.Errorf("splitChunks(%q, %d) = %q, which doesn't give back the text", tt.text, tt.size, got)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("splitChunks(%q, %d) = %q, want %q", tt.text, tt.size, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("splitChunks(%q, %d)[%d] = %q, want %q", tt.text, tt.size, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestTranslateChunks(t *testing.T) {
+	tr := &fakeTranslator{}
+	a := &app{tr: tr, format: "text", chunkSize: 20, concurrency: 3}
+	text := "primero uno.\n\nsegundo dos.\n\ntercero tres.\n\ncuarto cuatro.\n"
+	got, err := a.translate("en", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != strings.ToUpper(text) {
+		t.Errorf("translate = %q, want the chunks in order", got)
+	}
+	if len(tr.texts) != 4 {
+		t.Errorf("the backend got %q, want one request per paragraph", tr.texts)
+	}
+}
+
+// blockingTranslator fails on the texts starting with "fail" and waits for its context to be
+// canceled on the other ones
+type blockingTranslator struct {
+	ctx context.Context
+}
+
+var errChunk = errors.New("chunk failed")
+
+func (b blockingTranslator) WithContext(ctx context.Context) translate.Translator {
+	return blockingTranslator{ctx}
+}
+
+func (b blockingTranslator) Translate(target, text string) (string, error) {
+	if strings.HasPrefix(text, "fail") {
+		return "", errChunk
+	}
+	select {
+	case <-b.ctx.Done():
+		return "", b.ctx.Err()
+	case <-time.After(5 * time.Second):
+		return "", errors.New("the request was not canceled")
+	}
+}
+
+func (b blockingTranslator) Detect(text string) (string, float64, error) {
+	return "es", 1, nil
+}
+
+func TestTranslateChunksCanceled(t *testing.T) {
+	text := "wait one.\n\nwait two.\n\nfail three.\n\nwait four.\n"
+	a := &app{tr: blockingTranslator{context.Background()}, format: "text", chunkSize: 12, concurrency: 4}
+	start := time.Now()
+	if _, err := a.translate("en", text); !errors.Is(err, errChunk) {
+		t.Errorf("translate = %v, want %v", err, errChunk)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("the chunks in flight were not canceled when one failed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	a = a.withContext(ctx)
+	if _, err := a.translate("en", strings.ReplaceAll(text, "fail", "wait")); !errors.Is(err, context.Canceled) {
+		t.Errorf("translate = %v, want %v", err, context.Canceled)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("the chunks in flight were not canceled with the request")
+	}
+}
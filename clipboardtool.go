@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// clipboardTool is a pair of external commands reading and writing the clipboard,
+// given whether the primary selection should be used instead
+type clipboardTool struct {
+	paste func(primary bool) []string
+	copy  func(primary bool) []string
+}
+
+var clipboardTools = map[string]clipboardTool{
+	"pbcopy": {
+		paste: func(bool) []string { return []string{"pbpaste"} },
+		copy:  func(bool) []string { return []string{"pbcopy"} },
+	},
+	"wl-clipboard": {
+		paste: func(primary bool) []string {
+			return withFlag([]string{"wl-paste", "--no-newline"}, primary, "--primary")
+		},
+		copy: func(primary bool) []string { return withFlag([]string{"wl-copy"}, primary, "--primary") },
+	},
+	"xclip": {
+		paste: func(primary bool) []string { return []string{"xclip", "-out", "-selection", xSelection(primary)} },
+		copy:  func(primary bool) []string { return []string{"xclip", "-in", "-selection", xSelection(primary)} },
+	},
+	"xsel": {
+		paste: func(primary bool) []string { return []string{"xsel", "--output", "--" + xSelection(primary)} },
+		copy:  func(primary bool) []string { return []string{"xsel", "--input", "--" + xSelection(primary)} },
+	},
+}
+
+func withFlag(args []string, set bool, flag string) []string {
+	if set {
+		return append(args, flag)
+	}
+	return args
+}
+
+func xSelection(primary bool) string {
+	if primary {
+		return selPrimary
+	}
+	return selClipboard
+}
+
+// checkClipboardTool validates the value of -clipboard-tool
+func checkClipboardTool(name string) error {
+	if _, ok := clipboardTools[name]; ok || name == "" {
+		return nil
+	}
+	names := make([]string, 0, len(clipboardTools))
+	for n := range clipboardTools {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return fmt.Errorf("unknown clipboard tool %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+func (t clipboardTool) read(primary bool) (string, error) {
+	args := t.paste(primary)
+	out, err := exec.Command(args[0], args[1:]...).Output()
+	return string(out), err
+}
+
+func (t clipboardTool) write(text string, primary bool) error {
+	args := t.copy(primary)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
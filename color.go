@@ -0,0 +1,28 @@
+package main
+
+import "os"
+
+// the ANSI colors of the terminal output
+const (
+	colorRed   = "31"
+	colorGreen = "32"
+	colorGray  = "90"
+)
+
+// useColor reports whether the output written to f may be colored: f is a terminal, other than
+// a dumb one, and NO_COLOR is not set (https://no-color.org)
+func useColor(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in the escape sequences of color when enabled
+func paint(enabled bool, color, s string) string {
+	if !enabled {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"log/slog"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// translateCompared translates text into target with the chosen backend and, with -compare,
+// concurrently with the other one; a failure of the latter is only logged
+func (a *app) translateCompared(target, text string) (string, string, error) {
+	if a.compare == nil {
+		trans, err := a.translateOrNotify(target, text)
+		return trans, "", err
+	}
+	other := *a
+	other.tr = a.compare
+	var trans, alt string
+	var g errgroup.Group
+	g.Go(func() error {
+		var err error
+		trans, err = a.translateOrNotify(target, text)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		if alt, err = other.translate(target, text); err != nil {
+			slog.Warn("unable to translate", "backend", a.compareBackend, "err", err)
+			alt = "(failed)"
+		}
+		return nil
+	})
+	return trans, alt, g.Wait()
+}
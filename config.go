@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/text/language"
+)
+
+// Config holds the defaults that can be set in the config file
+type Config struct {
+	Known  string `toml:"known"`
+	Learn  string `toml:"learn"`
+	LLM    bool   `toml:"llm"`
+	Append bool   `toml:"append"`
+}
+
+func configPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "tclip", "config.toml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "tclip", "config.toml"), nil
+}
+
+// localeLanguage returns the language of the locale set by LC_ALL, LC_MESSAGES or LANG,
+// such as ko for ko_KR.UTF-8, falling back to en
+func localeLanguage() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale := os.Getenv(env)
+		if locale == "" {
+			continue
+		}
+		locale, _, _ = strings.Cut(locale, ".")
+		locale, _, _ = strings.Cut(locale, "@")
+		if locale == "C" || locale == "POSIX" {
+			break
+		}
+		tag, err := language.Parse(strings.ReplaceAll(locale, "_", "-"))
+		if err != nil {
+			break
+		}
+		base, _ := tag.Base()
+		if base.String() != "zh" {
+			return base.String()
+		}
+		// Google Translate tells the written forms of Chinese apart
+		if region, _ := tag.Region(); region.String() == "TW" || region.String() == "HK" || region.String() == "MO" {
+			return "zh-TW"
+		}
+		return "zh-CN"
+	}
+	return "en"
+}
+
+// createConfig returns the built-in defaults overridden by the config file, if any
+func createConfig() Config {
+	cfg := Config{Known: localeLanguage(), Learn: "ko"}
+	if cfg.Known == cfg.Learn {
+		// Korean speakers are not learning Korean
+		cfg.Learn = "en"
+	}
+	path, err := configPath()
+	if err != nil {
+		return cfg
+	}
+	if _, err := toml.DecodeFile(path, &cfg); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("unable to read the config file", "path", path, "err", err)
+	}
+	return cfg
+}
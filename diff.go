@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// diffTokens splits text into words, and the words written in Chinese or Japanese,
+// which has no spaces between them, into characters
+func diffTokens(text string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(text) {
+		if !strings.ContainsFunc(field, isIdeographic) {
+			tokens = append(tokens, field)
+			continue
+		}
+		for _, r := range field {
+			tokens = append(tokens, string(r))
+		}
+	}
+	return tokens
+}
+
+// isIdeographic reports whether r belongs to a script written without spaces
+func isIdeographic(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+// joinTokens joins tokens with spaces, except between the characters of Chinese or Japanese
+func joinTokens(tokens []string) string {
+	var out strings.Builder
+	for k, tok := range tokens {
+		if k > 0 {
+			// the diff markers don't count
+			prev, _ := utf8.DecodeLastRuneInString(strings.TrimRight(tokens[k-1], "+-]"))
+			next, _ := utf8.DecodeRuneInString(strings.TrimLeft(tok, "[+-"))
+			if !isIdeographic(prev) || !isIdeographic(next) {
+				out.WriteByte(' ')
+			}
+		}
+		out.WriteString(tok)
+	}
+	return out.String()
+}
+
+// sameToken reports whether two tokens are equal, leaving case and punctuation aside
+func sameToken(a, b string) bool {
+	trim := func(s string) string { return strings.TrimFunc(s, unicode.IsPunct) }
+	return strings.EqualFold(trim(a), trim(b))
+}
+
+// wordDiff returns back with the words missing from source marked as [+word+] and the words
+// of source missing from back marked as [-word-], from their longest common subsequence;
+// it returns an empty string when both texts have the same words
+func wordDiff(source, back string) string {
+	a, b := diffTokens(source), diffTokens(back)
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if sameToken(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	if lcs[0][0] == len(a) && len(a) == len(b) {
+		return ""
+	}
+
+	var out []string
+	var removed, added []string
+	flush := func() {
+		if len(removed) > 0 {
+			out = append(out, "[-"+joinTokens(removed)+"-]")
+		}
+		if len(added) > 0 {
+			out = append(out, "[+"+joinTokens(added)+"+]")
+		}
+		removed, added = nil, nil
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && sameToken(a[i], b[j]):
+			flush()
+			out = append(out, b[j])
+			i, j = i+1, j+1
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			added = append(added, b[j])
+			j++
+		default:
+			removed = append(removed, a[i])
+			i++
+		}
+	}
+	flush()
+	return joinTokens(out)
+}
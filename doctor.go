@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// errDoctor is returned by doctor when a check fails
+var errDoctor = errors.New("some checks failed")
+
+// checkup prints the outcome of the checks run by doctor, coloring their status if color is set
+type checkup struct {
+	w      io.Writer
+	color  bool
+	failed bool
+}
+
+func (c *checkup) ok(name, detail string) {
+	fmt.Fprintf(c.w, "%s    %s: %s\n", paint(c.color, colorGreen, "OK"), name, detail)
+}
+
+func (c *checkup) skip(name, detail string) {
+	fmt.Fprintf(c.w, "%s    %s: %s\n", paint(c.color, colorGray, "--"), name, detail)
+}
+
+func (c *checkup) fail(name, detail, hint string) {
+	c.failed = true
+	fmt.Fprintf(c.w, "%s  %s: %s\n      %s\n", paint(c.color, colorRed, "FAIL"), name, detail, hint)
+}
+
+// backendKeys lists the environment variable holding the API key of each backend
+var backendKeys = []struct{ backend, env string }{
+	{"nmt", "GOOGLE_TRANSLATE_APIKEY"},
+	{"gemini", "GEMINI_APIKEY"},
+	{"openai", "OPENAI_APIKEY"},
+	{"anthropic", "ANTHROPIC_APIKEY"},
+}
+
+// doctor checks the setup of tclip for backend: the API keys, the clipboard, the notifications
+// and the optional programs, printing OK or FAIL for each of them with a hint for the failures
+func (a *app) doctor(backend, keyFile string) error {
+	c := &checkup{w: os.Stdout, color: useColor(os.Stdout)}
+
+	for _, k := range backendKeys {
+		name := k.env
+		switch {
+		case os.Getenv(k.env) != "":
+			c.ok(name, "set")
+		case k.backend == backend && keyFile != "":
+			c.skip(name, "not set, reading the key from "+keyFile)
+		case k.backend == "nmt" && os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "":
+			c.skip(name, "not set, using GOOGLE_APPLICATION_CREDENTIALS")
+		case k.backend == backend:
+			c.fail(name, "not set, needed by the "+backend+" backend", "export "+k.env+"=<your key> or pass -keyfile")
+		default:
+			c.skip(name, "not set, only needed by -backend "+k.backend)
+		}
+	}
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			c.fail("GOOGLE_APPLICATION_CREDENTIALS", err.Error(), "point it to the JSON key file of a service account")
+		} else {
+			c.ok("GOOGLE_APPLICATION_CREDENTIALS", path)
+		}
+	}
+	if backend == "ollama" {
+		client := &http.Client{Timeout: 2 * time.Second}
+		if resp, err := client.Get(translate.OllamaHost() + "/api/tags"); err != nil {
+			c.fail("ollama", err.Error(), "start it with `ollama serve` or set OLLAMA_HOST")
+		} else {
+			resp.Body.Close()
+			c.ok("ollama", "reachable at "+translate.OllamaHost())
+		}
+	}
+
+	if _, err := a.readClipboard(false); err != nil {
+		c.fail("clipboard", err.Error(), "install wl-clipboard, xclip or xsel, or pick one with -clipboard-tool")
+	} else {
+		c.ok("clipboard", "readable")
+	}
+	if !hasPrimary {
+		c.skip("primary selection", "not available on this platform")
+	} else if _, err := a.readClipboard(true); err != nil {
+		c.fail("primary selection", err.Error(), "install xclip or xsel, or wl-clipboard on Wayland")
+	} else {
+		c.ok("primary selection", "readable")
+	}
+
+	if a.notify.unavailable != "" {
+		c.fail("notifications", a.notify.unavailable, "install libnotify (notify-send) and make sure a notification daemon is running")
+	} else if err := a.notify.push("tclip", "Notifications work", urgencyLow, 0); err != nil {
+		c.fail("notifications", err.Error(), "install libnotify (notify-send) and make sure a notification daemon is running")
+	} else if a.notify.notifySend != "" {
+		c.ok("notifications", "sent with "+a.notify.notifySend)
+	} else {
+		c.ok("notifications", "sent")
+	}
+
+	for _, tool := range []struct{ name, flag string }{
+		{"tesseract", "-ocr"},
+		{"fzf", "-pick"},
+	} {
+		if path, err := exec.LookPath(tool.name); err == nil {
+			c.ok(tool.name, path)
+		} else {
+			c.skip(tool.name, "not installed, used by "+tool.flag)
+		}
+	}
+	if cmd, err := speechCommand("en", ""); err == nil {
+		c.ok("text-to-speech", cmd.Path)
+	} else {
+		c.skip("text-to-speech", "not installed, used by -speak")
+	}
+
+	if c.failed {
+		return errDoctor
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// the exit codes of tclip, listed in -help
+const (
+	exitOK = iota
+	exitFailure
+	// exitUsage matches the code used by the flag package for invalid flags
+	exitUsage
+	exitClipboard
+	exitNoText
+	exitAuth
+	exitAPI
+	exitTimeout
+	exitLimit
+)
+
+const exitCodesHelp = `
+Exit codes:
+  0  the text was translated
+  1  any other error
+  2  invalid flags
+  3  the clipboard could not be read
+  4  the selection is empty
+  5  the API key is missing or was rejected
+  6  the translation API returned an error
+  7  the request timed out
+  8  the API rate limit or quota was reached
+`
+
+// codeError attaches an exit code to an error
+type codeError struct {
+	code int
+	err  error
+}
+
+func (e *codeError) Error() string { return e.err.Error() }
+
+func (e *codeError) Unwrap() error { return e.err }
+
+// withCode wraps err so that tclip exits with code, keeping nil errors nil
+func withCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codeError{code, err}
+}
+
+// exitCode maps the error that stopped tclip to its exit code
+func exitCode(err error) int {
+	var cErr *codeError
+	var netErr net.Error
+	_, limited := translate.LimitOf(err)
+	switch code := translate.StatusCode(err); {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	case errors.Is(err, errNoText):
+		return exitNoText
+	case limited:
+		return exitLimit
+	case errors.Is(err, translate.ErrNoAPIKey), code == http.StatusUnauthorized, code == http.StatusForbidden:
+		return exitAuth
+	case errors.As(err, &cErr):
+		return cErr.code
+	case code != 0, errors.As(err, &netErr):
+		return exitAPI
+	}
+	return exitFailure
+}
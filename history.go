@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+)
+
+const historyShown = 20
+
+// historyEntry is a translation as stored in the history file
+type historyEntry struct {
+	Time time.Time `json:"time"`
+	result
+}
+
+// record appends res to the history, if enabled, only logging failures
+func (a *app) record(res result) {
+	if a.historyFile == "" {
+		return
+	}
+	if err := appendJSONLine(a.historyFile, historyEntry{Time: time.Now(), result: res}); err != nil {
+		slog.Warn("unable to write the history", "err", err)
+	}
+}
+
+// printHistory prints the last n translations of the history file at path
+func printHistory(path string, n int) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Println("no translations recorded yet")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var e historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+		if len(entries) > n {
+			entries = entries[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		fmt.Printf("%s [%s] %s -> %s\n%s\n%s\n\n", e.Time.Format(time.DateTime), e.Backend,
+			e.DetectedLanguage, e.TargetLanguage, e.Source, e.Translation)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"html"
+	"strings"
+)
+
+// serveSelectionCmd is the internal subcommand of tclip serving the selection named by its argument,
+// clipboard or primary, with the richContent read from stdin; it is started by writeHTMLSelection
+const serveSelectionCmd = "serve-selection"
+
+// richContent is the content of a selection offered both as text and as HTML
+type richContent struct {
+	Text string `json:"text"`
+	HTML string `json:"html"`
+}
+
+// htmlTarget formats the source and the translation of res as HTML, for the applications pasting rich text
+func htmlTarget(res result) string {
+	return `<meta charset="utf-8">` + htmlBlock("blockquote", res.DetectedLanguage, res.Source) +
+		htmlBlock("p", res.TargetLanguage, res.Translation)
+}
+
+// htmlBlock returns the escaped text wrapped in tag, in the language lang unless it lists several
+func htmlBlock(tag, lang, text string) string {
+	attr := ""
+	if lang != "" && !strings.Contains(lang, ",") {
+		attr = ` lang="` + html.EscapeString(lang) + `"`
+	}
+	text = strings.ReplaceAll(html.EscapeString(strings.TrimSpace(text)), "\n", "<br>")
+	return "<" + tag + attr + ">" + text + "</" + tag + ">"
+}
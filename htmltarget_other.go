@@ -0,0 +1,15 @@
+//go:build darwin || windows
+
+package main
+
+import "errors"
+
+var errNoHTMLTarget = errors.New("the text/html target is only available on X11")
+
+func (a *app) writeHTMLSelection(trans, html string) error {
+	return errNoHTMLTarget
+}
+
+func serveSelection(args []string) error {
+	return errNoHTMLTarget
+}
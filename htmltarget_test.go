@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestHTMLTarget(t *testing.T) {
+	tests := []struct {
+		res  result
+		want string
+	}{
+		{
+			result{Source: "hola <mundo> & más\n", Translation: "hello <world> & more", DetectedLanguage: "es", TargetLanguage: "en"},
+			`<meta charset="utf-8"><blockquote lang="es">hola &lt;mundo&gt; &amp; más</blockquote><p lang="en">hello &lt;world&gt; &amp; more</p>`,
+		},
+		{
+			result{Source: "안녕\n친구", Translation: "en: hi\nfriend\nes: hola\namigo", DetectedLanguage: "ko", TargetLanguage: "en,es"},
+			`<meta charset="utf-8"><blockquote lang="ko">안녕<br>친구</blockquote><p>en: hi<br>friend<br>es: hola<br>amigo</p>`,
+		},
+		{
+			result{Source: "x", Translation: "y"},
+			`<meta charset="utf-8"><blockquote>x</blockquote><p>y</p>`,
+		},
+	}
+	for _, tt := range tests {
+		if got := htmlTarget(tt.res); got != tt.want {
+			t.Errorf("htmlTarget(%+v) = %q, want %q", tt.res, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+//go:build freebsd || linux || netbsd || openbsd || solaris || dragonfly
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// writeHTMLSelection writes trans to the chosen selection along with html, served by the
+// serve-selection subcommand left in the background until another application takes the selection;
+// it talks to the X server itself, so the selection must fit in a single request and neither
+// -clipboard-tool nor the wl-clipboard fallback apply
+func (a *app) writeHTMLSelection(trans, html string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(richContent{Text: trans, HTML: html})
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, serveSelectionCmd, a.writeTo)
+	cmd.Stdin = bytes.NewReader(content)
+	// the server outlives the terminal tclip runs in
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// the server writes ok once it owns the selection, or why it couldn't
+	line, _ := bufio.NewReader(out).ReadString('\n')
+	if line = strings.TrimSpace(line); line != "ok" {
+		cmd.Wait()
+		if line == "" {
+			line = "the selection server exited"
+		}
+		return errors.New(line)
+	}
+	// reaped once another application takes the selection, if tclip still runs in watch mode
+	go cmd.Wait()
+	return nil
+}
+
+// serveSelection runs the serve-selection subcommand with args: it owns the selection they name
+// with the content read from stdin, for writeHTMLSelection, until another application takes it;
+// the errors are written to stdout, where writeHTMLSelection waits for ok
+func serveSelection(args []string) error {
+	if len(args) != 1 || args[0] != selClipboard && args[0] != selPrimary {
+		err := errors.New("usage: tclip " + serveSelectionCmd + " clipboard|primary, with the content on stdin")
+		fmt.Println(err)
+		return err
+	}
+	selection := args[0]
+	var content richContent
+	if err := json.NewDecoder(os.Stdin).Decode(&content); err != nil {
+		fmt.Println(err)
+		return err
+	}
+	atom := "CLIPBOARD"
+	if selection == selPrimary {
+		atom = "PRIMARY"
+	}
+	ready := false
+	err := ownSelection(atom, content, func() {
+		ready = true
+		fmt.Println("ok")
+		os.Stdout.Close()
+	})
+	if err != nil && !ready {
+		fmt.Println(err)
+	}
+	return err
+}
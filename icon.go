@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// iconCandidates are the notification icons tried when -icon is not set, relative to the XDG data
+// directories: the region settings icon of GNOME, then the locale icon of the Adwaita, Breeze
+// and hicolor themes, as found on GNOME, KDE and XFCE
+var iconCandidates = []string{
+	"icons/hicolor/scalable/apps/org.gnome.Settings-region-symbolic.svg",
+	"icons/Adwaita/scalable/apps/preferences-desktop-locale-symbolic.svg",
+	"icons/Adwaita/symbolic/apps/preferences-desktop-locale-symbolic.svg",
+	"icons/breeze/preferences/32/preferences-desktop-locale.svg",
+	"icons/Adwaita/48x48/apps/preferences-desktop-locale.png",
+	"icons/hicolor/48x48/apps/preferences-desktop-locale.png",
+}
+
+// dataDirs returns the XDG data directories, the one of the user first
+func dataDirs() []string {
+	var dirs []string
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		dirs = append(dirs, dir)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".local", "share"))
+	}
+	system := os.Getenv("XDG_DATA_DIRS")
+	if system == "" {
+		system = "/usr/local/share:/usr/share"
+	}
+	return append(dirs, filepath.SplitList(system)...)
+}
+
+// findIcon returns the first of paths that exists, looking the relative ones up in the XDG
+// data directories, or an empty string when none does
+func findIcon(paths []string) string {
+	dirs := dataDirs()
+	for _, path := range paths {
+		if filepath.IsAbs(path) {
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+			continue
+		}
+		for _, dir := range dirs {
+			if _, err := os.Stat(filepath.Join(dir, path)); err == nil {
+				return filepath.Join(dir, path)
+			}
+		}
+	}
+	return ""
+}
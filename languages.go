@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// printLanguages prints the languages whose name or tag contains filter, ignoring case
+func printLanguages(langs []translate.Language, filter string, asJSON bool) error {
+	type entry struct {
+		Tag  string `json:"tag"`
+		Name string `json:"name"`
+	}
+	filter = strings.ToLower(filter)
+	var entries []entry
+	for _, lang := range langs {
+		e := entry{Tag: lang.Tag.String(), Name: lang.Name}
+		if strings.Contains(strings.ToLower(e.Tag), filter) || strings.Contains(strings.ToLower(e.Name), filter) {
+			entries = append(entries, e)
+		}
+	}
+	if asJSON {
+		return json.NewEncoder(os.Stdout).Encode(entries)
+	}
+	for i, e := range entries {
+		fmt.Printf("%3d - %s: %s\n", i, e.Tag, e.Name)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// runLines translates every non-blank line of text on its own, printing each one
+// as its source and translations separated by tabs; the language is detected once for all of them
+func (a *app) runLines(text string) error {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return errNoText
+	}
+
+	detected, confidence := a.source, 1.0
+	var err error
+	if detected == "" {
+		detected, confidence, err = a.detectLanguage(a.detectionSample(strings.Join(lines, "\n")))
+	}
+	if err != nil {
+		return withCode(exitAPI, err)
+	}
+	slog.Info("detected language", "lang", translate.LanguageLabel(detected), "confidence", confidence)
+	detected, ok := a.snapCandidate(strings.Join(lines, "\n"), detected)
+	targets := a.targets(detected)
+	if !ok || confidence < a.minConfidence {
+		targets = a.learn
+	}
+
+	results := make([][]string, len(lines))
+	g, req := a.group()
+	for i, line := range lines {
+		if req.ctx.Err() != nil {
+			break
+		}
+		results[i] = make([]string, len(targets))
+		g.Go(func() error {
+			for j, target := range targets {
+				trans, err := req.translate(target, a.normalize(line))
+				if err != nil {
+					return err
+				}
+				if !a.noPostprocess {
+					trans = postprocess(target, trans)
+				}
+				results[i][j] = trans
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return withCode(exitAPI, err)
+	}
+
+	w := a.output()
+	for i, line := range lines {
+		if a.json {
+			res := result{
+				Source:           line,
+				Translation:      strings.Join(results[i], "\n"),
+				DetectedLanguage: detected,
+				Confidence:       confidence,
+				TargetLanguage:   strings.Join(targets, ","),
+				Backend:          a.backend,
+			}
+			if err := json.NewEncoder(w).Encode(res); err != nil {
+				return err
+			}
+			continue
+		}
+		fields := []string{line}
+		for _, trans := range results[i] {
+			// keep every line of the output a single row
+			fields = append(fields, strings.Join(strings.Fields(trans), " "))
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(fields, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
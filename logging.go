@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// setupLogging sends the log records of at least level to stderr, lowering it to info when verbose;
+// they are never colored, so that they stay easy to scrape
+func setupLogging(level string, verbose bool) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("unknown log level %q, expected debug, info, warn or error", level)
+	}
+	if verbose {
+		lvl = min(lvl, slog.LevelInfo)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: lvl})))
+	return nil
+}
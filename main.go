@@ -1,204 +1,987 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
-
-	"golang.org/x/text/language"
-	"google.golang.org/api/option"
-
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
-	"cloud.google.com/go/translate"
-	"github.com/0xAX/notificator"
-	"github.com/arrufat/clipboard"
-	"github.com/google/generative-ai-go/genai"
-	"html"
+	"github.com/arrufat/tclip/pkg/translate"
 )
 
-// GTranslate groups the client and the context needed for translation
-type GTranslate struct {
-	nmtClient *translate.Client
-	llmClient *genai.Client
-	llm       *genai.GenerativeModel
-	ctx       context.Context
+// defaultSeparator goes between the text and the translation with -append
+const defaultSeparator = "\n---\n"
+
+// fallbackReporter is implemented by the translators that can stand in for a failing backend
+type fallbackReporter interface {
+	// UsedFallback reports whether any request so far was served by the fallback
+	UsedFallback() bool
+}
+
+// app holds the state shared by every translation of a selection
+type app struct {
+	tr     translate.Translator
+	notify *notifier
+	// urgency is the urgency of the notifications that are not errors
+	urgency string
+	// notifyTimeout and errorTimeout are how long translations and errors stay on screen,
+	// left to the daemon when zero
+	notifyTimeout time.Duration
+	errorTimeout  time.Duration
+	// notifyPreview is the number of characters of the translations shown in the notifications, all when zero
+	notifyPreview int
+	// speak reads the translations aloud
+	speak bool
+	// compare also translates with compareBackend, shown next to the translation
+	compare        translate.Translator
+	compareBackend string
+	// noPostprocess leaves the translations as the backend returned them, without postRules
+	noPostprocess bool
+	// minCopyConfidence is the detection confidence below which the translation is only shown,
+	// unless force is set
+	minCopyConfidence float64
+	force             bool
+	// maxChars is the length above which selections are not translated unless force is set
+	maxChars int
+	// fromImage is set when the text was extracted from a clipboard image
+	fromImage bool
+	// ocrLangs are the tesseract languages of the clipboard images, the default one when empty
+	ocrLangs string
+	// pick asks the user for the target language after the detection
+	pick bool
+	// localDetect detects the language from the script alone, without requests
+	localDetect bool
+	// paraphrase rewrites the text in its own language instead of translating it
+	paraphrase bool
+	// transliterate writes the romanization of the text instead of translating it
+	transliterate bool
+	// segment translates every language run of the text into its own target
+	segment bool
+	// reverse swaps the direction chosen by targets
+	reverse bool
+	// candidates are the only languages the detection may return, any when empty
+	candidates []string
+	// noSkip translates even the text that looks like a URL, an email address or a path
+	noSkip  bool
+	known   string
+	learn   []string
+	backend string
+	concat  bool
+	// prepend puts the translation before the source when appending
+	prepend bool
+	// separator goes between the source and the translation when appending
+	separator string
+	json      bool
+	format    string
+	// normalized cleans up the whitespace of the text before translating it
+	normalized bool
+	// straightQuotes also replaces smart quotes when normalizing
+	straightQuotes bool
+	// source is the language of the text when set, skipping detection
+	source string
+	// minConfidence is the detection confidence below which the text is translated into learn
+	minConfidence float64
+	// verify translates the result back into the source language
+	verify bool
+	// ipa adds the IPA transcription of single words
+	ipa bool
+	// romanized adds the romanization of Chinese, Japanese and Korean translations
+	romanized bool
+	// historyFile is the path of the translation history, disabled when empty
+	historyFile string
+	// readFrom and writeTo are the selections the text is read from and the translation written to,
+	// clipboard or primary, as resolved from -selection, -read-selection and -write-selection
+	readFrom string
+	writeTo  string
+	// splitSelections writes the source to the primary selection and the translation to the clipboard
+	splitSelections bool
+	// htmlTarget also offers the source and the translation as text/html on X11
+	htmlTarget bool
+	// nativeClipboard falls back to pbpaste and pbcopy on macOS when the library fails
+	nativeClipboard bool
+	// clipboardTool replaces the library with one of clipboardTools when set
+	clipboardTool string
+	// dryRun translates without writing to the clipboard
+	dryRun bool
+	// keep only reports the translation, leaving the original text in the clipboard
+	keep bool
+	// quiet writes to stderr instead of showing notifications
+	quiet bool
+	// out receives the translation instead of the clipboard and notifications when set
+	out io.Writer
+	// chunkSize is the maximum number of bytes sent in a single request, no limit when zero
+	chunkSize int
+	// concurrency is the number of chunks translated in parallel
+	concurrency int
+	// ctx is canceled when a newer selection replaces the one being translated in watch mode,
+	// nil otherwise
+	ctx context.Context
+}
+
+// result describes a translation, as printed by -json
+type result struct {
+	Source           string  `json:"source"`
+	Translation      string  `json:"translation"`
+	DetectedLanguage string  `json:"detected_language,omitempty"`
+	Confidence       float64 `json:"confidence,omitempty"`
+	TargetLanguage   string  `json:"target_language"`
+	Backend          string  `json:"backend"`
+	BackTranslation  string  `json:"back_translation,omitempty"`
+	Romanization     string  `json:"romanization,omitempty"`
+	Pronunciation    string  `json:"pronunciation,omitempty"`
+	Comparison       string  `json:"comparison,omitempty"`
 }
 
-func createClientWithKey(useLLM bool) (*GTranslate, error) {
-	ctx := context.Background()
-	if useLLM {
-		client, err := genai.NewClient(ctx, option.WithAPIKey(os.Getenv("GEMINI_APIKEY")))
+// run translates text, writes the result to the clipboard and returns what was written
+func (a *app) run(text string) (string, error) {
+	slog.Info("selected text", "text", text)
+	text = a.stripAppended(text)
+	res := result{Source: text, Backend: a.backend}
+	original := text
+	text = a.normalize(text)
+	if !a.noSkip {
+		if kind := untranslatable(text); kind != "" {
+			slog.Info("copying the text unchanged", "kind", kind)
+			res.Translation = original
+			if err := a.deliver(res, original, original, !a.keep); err != nil {
+				return "", err
+			}
+			a.push("Not translated", fmt.Sprintf("The text is %s: %s", kind, original))
+			return original, nil
+		}
+	}
+	if a.paraphrase {
+		return a.runParaphrase(original, text)
+	}
+	if a.transliterate {
+		return a.runTransliterate(original, text)
+	}
+	if a.segment {
+		return a.runSegments(original, text)
+	}
+	var err error
+	det := ""
+	var targets []string
+	// unsure is set when the detected language is not trusted enough to pick the targets
+	unsure := false
+	if a.source != "" {
+		// the source language is assumed, not detected
+		res.DetectedLanguage, res.Confidence = a.source, 1
+	} else {
+		res.DetectedLanguage, res.Confidence, err = a.detectLanguage(a.detectionSample(text))
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		a.pushError("Error", "Translation timed out")
+		return "", err
+	} else if limit, ok := translate.LimitOf(err); ok {
+		a.pushError("Error", "Unable to detect the language: "+limit.Message())
+		return "", err
+	} else if err != nil {
+		a.pushError("Error", "Unable to detect the language")
+		return "", withCode(exitAPI, err)
+	} else {
+		slog.Info("detected language", "lang", translate.LanguageLabel(res.DetectedLanguage), "confidence", res.Confidence)
+		lang, ok := a.snapCandidate(text, res.DetectedLanguage)
+		if lang != res.DetectedLanguage {
+			slog.Info("snapped the detected language to a candidate", "lang", translate.LanguageLabel(lang))
+		}
+		res.DetectedLanguage = lang
+		targets = a.targets(res.DetectedLanguage)
+		det = "from " + translate.LanguageLabel(res.DetectedLanguage)
+		if !ok {
+			slog.Info("detected language is not a candidate", "targets", strings.Join(a.learn, ","))
+			targets, unsure = a.learn, true
+			det = fmt.Sprintf("from %s (not a candidate)", translate.LanguageLabel(res.DetectedLanguage))
+		} else if res.Confidence < a.minConfidence {
+			slog.Info("low confidence detection", "targets", strings.Join(a.learn, ","))
+			targets, unsure = a.learn, true
+			det = fmt.Sprintf("from %s (low confidence: %.0f%%)", translate.LanguageLabel(res.DetectedLanguage), 100*res.Confidence)
+		}
+	}
+	if a.pick {
+		target, err := a.pickTarget()
 		if err != nil {
-			log.Fatal(err)
+			return "", err
 		}
-		llm := client.GenerativeModel("gemini-1.5-flash")
-		llm.SystemInstruction = &genai.Content{
-			Parts: []genai.Part{genai.Text("You are a language translator.\n" +
-				"Whenever you receive a message, you will only respond with a translated version of the message.\n" +
-				"The rules are as follows: if the message is in English, translate it into Korean, otherwise, translate it into English.\n" +
-				"You should strive for accuracy on the meaning and not on a literal translation.\n" +
-				"Remember: the output should only contain the translated message.")},
+		targets = []string{target}
+	}
+	if res.DetectedLanguage != "" && !unsure {
+		// translating into the language of the text would give the same text back
+		targets = slices.DeleteFunc(targets, func(target string) bool {
+			return translate.SameLanguage(target, res.DetectedLanguage)
+		})
+		if len(targets) == 0 {
+			slog.Info("the text is already in the target language", "lang", translate.LanguageLabel(res.DetectedLanguage))
+			res.Translation, res.TargetLanguage = original, res.DetectedLanguage
+			if err := a.deliver(res, original, original, false); err != nil {
+				return "", err
+			}
+			a.push("Not translated", "The text is already in "+translate.LanguageName(res.DetectedLanguage))
+			return original, nil
 		}
-		return &GTranslate{nmtClient: nil, llmClient: client, llm: llm, ctx: ctx}, err
-	} else {
-		client, err := translate.NewClient(ctx, option.WithAPIKey(os.Getenv("GOOGLE_TRANSLATE_APIKEY")))
+	}
+	res.TargetLanguage = strings.Join(targets, ",")
+	var translations []string
+	var copied []string
+	var shown []string
+	var backs []string
+	var romans []string
+	var ipas []string
+	var comparisons []string
+	spoken := make(map[string]string)
+	for _, target := range targets {
+		trans, alt, err := a.translateCompared(target, text)
 		if err != nil {
-			return nil, err
+			return "", err
+		}
+		if !a.noPostprocess {
+			trans, alt = postprocess(target, trans), postprocess(target, alt)
+		}
+		slog.Info("translated text", "target", target, "text", trans)
+		roman := a.romanize(target, trans)
+		ipa := a.pronounce(res.DetectedLanguage, text, target, trans)
+		spoken[target] = trans
+		// the back-translation is only shown, it is neither copied nor recorded
+		var verified string
+		if a.verify && res.DetectedLanguage != "" {
+			back, err := a.translateOrNotify(res.DetectedLanguage, trans)
+			if err != nil {
+				return "", err
+			}
+			slog.Info("back-translated text", "target", res.DetectedLanguage, "text", back)
+			backs = append(backs, back)
+			verified = "\nBack-translation: " + back
+			if diff := wordDiff(text, back); diff != "" {
+				verified += "\nDiff: " + diff
+			}
+		}
+		if len(targets) > 1 {
+			trans = target + ": " + trans
 		}
-		return &GTranslate{nmtClient: client, llmClient: nil, llm: nil, ctx: ctx}, err
+		translations = append(translations, trans)
+		if alt != "" {
+			slog.Info("translated text", "backend", a.compareBackend, "target", target, "text", alt)
+			comparisons = append(comparisons, alt)
+			trans = a.backend + ": " + trans + "\n" + a.compareBackend + ": " + alt
+		}
+		if roman != "" {
+			romans = append(romans, roman)
+			trans += "\n" + roman
+		}
+		if ipa != "" {
+			ipas = append(ipas, ipa)
+			trans += "\n" + ipa
+		}
+		copied = append(copied, trans)
+		shown = append(shown, trans+verified)
 	}
+	res.Translation = strings.Join(translations, "\n")
+	res.BackTranslation = strings.Join(backs, "\n")
+	res.Romanization = strings.Join(romans, "\n")
+	res.Pronunciation = strings.Join(ipas, "\n")
+	res.Comparison = strings.Join(comparisons, "\n")
+	if fb, ok := a.tr.(fallbackReporter); ok && fb.UsedFallback() {
+		det += " (with the nmt fallback)"
+	}
+	trans := res.Translation
+	display := strings.Join(shown, "\n")
+	if a.concat {
+		// the romanization only reaches the clipboard when appending
+		if a.prepend {
+			trans = strings.Join(copied, "\n") + a.separator + original
+			display = display + a.separator + original
+		} else {
+			trans = original + a.separator + strings.Join(copied, "\n")
+			display = original + a.separator + display
+		}
+	}
+	write := !a.keep
+	if write && res.Confidence < a.minCopyConfidence && !a.force {
+		slog.Warn("low confidence detection, leaving the clipboard untouched", "confidence", res.Confidence)
+		det += " (not copied, pass -force to copy it)"
+		write = false
+	}
+	if err := a.deliver(res, trans, display, write); err != nil {
+		return "", err
+	}
+	if a.fromImage && !a.concat {
+		// the text extracted from an image is nowhere else to be seen
+		display = original + a.separator + display
+	}
+	a.push(fmt.Sprintf("Translating %s: %s", det, original), display)
+	a.record(res)
+	if a.speak {
+		for _, target := range targets {
+			if err := speak(target, spoken[target]); err != nil {
+				slog.Warn("unable to speak the translation", "err", err)
+			}
+		}
+	}
+	return trans, nil
 }
 
-func (gt *GTranslate) useLLM() bool {
-	return gt.llmClient != nil
+// stripAppended returns the original text of a selection holding a previous result of -append or
+// -prepend, so that translating it again doesn't keep growing the clipboard
+func (a *app) stripAppended(text string) string {
+	if !a.concat || a.separator == "" {
+		return text
+	}
+	var original string
+	var ok bool
+	if a.prepend {
+		_, original, ok = cutLast(text, a.separator)
+	} else {
+		original, _, ok = strings.Cut(text, a.separator)
+	}
+	if !ok || strings.TrimSpace(original) == "" {
+		return text
+	}
+	slog.Info("translating only the original text of a previous result")
+	return original
 }
 
-func (gt *GTranslate) close() {
-	if gt.nmtClient != nil {
-		gt.nmtClient.Close()
-	}
-	if gt.llmClient != nil {
-		gt.llmClient.Close()
+// cutLast is strings.Cut around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
 	}
+	return s, "", false
 }
 
-func (gt *GTranslate) translate(targetLang, text string) (string, error) {
-	lang, err := language.Parse(targetLang)
-	if err != nil {
-		return "", err
+// deliver writes res as JSON or display as text to the output, and trans to the selection if write is set;
+// nothing is written once the request was canceled
+func (a *app) deliver(res result, trans, display string, write bool) error {
+	if a.canceled() {
+		return a.ctx.Err()
 	}
-	trans := ""
-	if gt.nmtClient != nil {
-		resp, err := gt.nmtClient.Translate(gt.ctx, []string{text}, lang, &translate.Options{Model: "nmt"})
-		if err != nil {
-			return "", err
+	if a.json {
+		if err := json.NewEncoder(a.output()).Encode(res); err != nil {
+			return err
 		}
-		trans = html.UnescapeString(resp[0].Text)
-	} else if gt.llmClient != nil {
-		resp, err := gt.llm.GenerateContent(gt.ctx, genai.Text(text))
-		if err != nil {
-			return "", err
+	} else if a.out != nil {
+		// the output ends like the input, so that editors can replace their selection with it
+		ending := res.Source[len(strings.TrimRight(res.Source, "\r\n")):]
+		display = strings.TrimRight(display, "\r\n") + ending
+		if _, err := io.WriteString(a.out, display); err != nil {
+			return err
 		}
-		trans = html.UnescapeString(fmt.Sprintf("%s", resp.Candidates[0].Content.Parts[0]))
-	} else {
-		return "", err
 	}
-	return trans, err
+	if a.dryRun {
+		slog.Info("dry run, leaving the clipboard untouched")
+	} else if a.out == nil && write && a.splitSelections {
+		return a.writeSplit(res.Source, trans)
+	} else if a.out == nil && write && a.htmlTarget {
+		err := a.writeHTMLSelection(trans, htmlTarget(res))
+		if err == nil {
+			return nil
+		}
+		slog.Warn("unable to offer the text/html target, writing only the text", "err", err)
+		return a.writeSelection(trans)
+	} else if a.out == nil && write {
+		return a.writeSelection(trans)
+	}
+	return nil
 }
 
-func (gt *GTranslate) detect(text string) (string, error) {
-	lang, err := gt.nmtClient.DetectLanguage(gt.ctx, []string{text})
+// detect reports the language of text without translating it
+func (a *app) detect(text string) error {
+	lang, confidence, err := a.detectLanguage(text)
 	if err != nil {
-		return "", err
+		a.pushError("Error", "Unable to detect the language")
+		return withCode(exitAPI, err)
+	}
+	if a.json {
+		return json.NewEncoder(a.output()).Encode(result{Source: text, DetectedLanguage: lang, Confidence: confidence, Backend: a.backend})
 	}
-	return fmt.Sprint(lang[0][0].Language), err
+	msg := fmt.Sprintf("%s, confidence %.0f%%", translate.LanguageLabel(lang), 100*confidence)
+	fmt.Fprintln(a.output(), msg)
+	a.push("Detected language: "+msg, text)
+	return nil
+}
+
+// romanizer is implemented by the translators able to romanize text
+type romanizer interface {
+	Romanize(lang, text string) (string, error)
 }
 
-func (gt *GTranslate) SupportedLanguages(targetLang string) error {
-	lang, err := language.Parse(targetLang)
+// romanize returns the romanization of trans when requested and target is Chinese, Japanese or Korean
+func (a *app) romanize(target, trans string) string {
+	r, ok := a.tr.(romanizer)
+	if !a.romanized || !ok || !translate.IsCJK(target) {
+		return ""
+	}
+	roman, err := r.Romanize(target, trans)
 	if err != nil {
-		return err
+		slog.Warn("unable to romanize the translation", "err", err)
+		return ""
 	}
-	if gt.nmtClient != nil {
-		resp, err := gt.nmtClient.SupportedLanguages(gt.ctx, lang)
-		if err != nil {
-			return err
+	return roman
+}
+
+// translateOrNotify translates text into target, notifying the user on failure
+func (a *app) translateOrNotify(target, text string) (string, error) {
+	trans, err := a.translate(target, text)
+	if limit, ok := translate.LimitOf(err); ok {
+		a.pushError("Error", "Unable to translate the language: "+limit.Message())
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		a.pushError("Error", "Translation timed out")
+	} else if err != nil {
+		a.pushError("Error", "Unable to translate the language")
+	}
+	return trans, withCode(exitAPI, err)
+}
+
+// targets returns the languages to translate into, given the detected language:
+// each language being learned when the text is in the known language, the known one otherwise,
+// or the other way around with reverse, unless that means translating into the detected language
+func (a *app) targets(detected string) []string {
+	var targets []string
+	for _, learn := range a.learn {
+		target := a.known
+		if (detected == a.known) != a.reverse {
+			target = learn
 		}
-		for i, lang := range resp {
-			fmt.Printf("%3d - %s: %s\n", i, lang.Tag, lang.Name)
+		if a.reverse && target == detected {
+			continue
 		}
-		return err
+		if !slices.Contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+	if len(targets) == 0 {
+		reversed := *a
+		reversed.reverse = false
+		return reversed.targets(detected)
+	}
+	return targets
+}
+
+// output returns where the results printed to stdout go
+func (a *app) output() io.Writer {
+	if a.out != nil {
+		return a.out
 	}
-	return errors.New("The nmtClient was not intialized")
+	return os.Stdout
+}
+
+// push notifies the user with the urgency given by -urgency
+func (a *app) push(title, text string) {
+	a.pushUrgency(title, preview(text, a.notifyPreview), a.urgency, a.notifyTimeout)
+}
+
+// preview truncates text to its first n characters followed by an ellipsis, keeping it whole when n is zero
+func preview(text string, n int) string {
+	runes := []rune(text)
+	if n <= 0 || len(runes) <= n {
+		return text
+	}
+	return strings.TrimRightFunc(string(runes[:n]), unicode.IsSpace) + "…"
+}
+
+// pushError notifies the user of a failure with critical urgency, unless the request was canceled
+func (a *app) pushError(title, text string) {
+	if a.canceled() {
+		return
+	}
+	a.pushUrgency(title, text, urgencyCritical, a.errorTimeout)
+}
+
+// pushUrgency shows a notification expiring after expire, or writes it to stderr in quiet mode or when notifications
+// are unavailable, unless the output is meant for scripts
+func (a *app) pushUrgency(title, text, urgency string, expire time.Duration) {
+	if a.json || a.out != nil {
+		return
+	}
+	if a.quiet || a.notify.unavailable != "" {
+		fmt.Fprintf(os.Stderr, "%s\n%s\n", title, text)
+		return
+	}
+	if err := a.notify.push(title, text, urgency, expire); err != nil {
+		// the next notifications won't fare better
+		slog.Warn("unable to show the notification, writing the notifications to stderr", "err", err)
+		a.notify.unavailable = err.Error()
+		fmt.Fprintf(os.Stderr, "%s\n%s\n", title, text)
+	}
+}
+
+// unescapeEntities reports whether the HTML entities of the translations are decoded: as asked by
+// -unescape, but never with -raw nor in markdown, where they are more likely meant literally
+func unescapeEntities(unescape, raw bool, format string) bool {
+	return unescape && !raw && format != "markdown"
 }
 
 func main() {
-	known := flag.String("k", "en", "the language you already know")
-	learn := flag.String("l", "ko", "the language you are learning")
-	useLLM := flag.Bool("llm", false, "use an LLM for translation")
-	concat := flag.Bool("append", false, "append the translation")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage of %s:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprint(out, exitCodesHelp)
+	}
+	if len(os.Args) > 1 && os.Args[1] == serveSelectionCmd {
+		// the internal subcommand started by writeHTMLSelection
+		if err := serveSelection(os.Args[2:]); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+	if err := tclip(); err != nil {
+		fmt.Fprintln(os.Stderr, "tclip:", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// tclip runs the command line, returning the error that determines the exit code
+func tclip() (err error) {
+	cfg := createConfig()
+	known := flag.String("k", cfg.Known, "the language you already know")
+	learn := flag.String("l", cfg.Learn, "the language you are learning, or a comma-separated list of them")
+	fallback := flag.String("fallback", "", "the backend used when the LLM backend fails: nmt, or empty for none")
+	useLLM := flag.Bool("llm", cfg.LLM, "use an LLM for translation (same as -backend gemini)")
+	backend := flag.String("backend", "", "the translation backend: nmt, gemini, ollama, openai, anthropic or exec")
+	execCmd := flag.String("exec-cmd", "", "the command of the exec backend, e.g. \"mytool {{source}} {{target}}\", reading the text on stdin")
+	model := flag.String("model", "", "the model used by the LLM backends (default gemini-1.5-flash for gemini, llama3 for ollama, gpt-4o-mini for openai, claude-3-5-haiku-latest for anthropic)")
+	temperature := flag.Float64("temperature", 0.2, "the sampling temperature of the LLM backends")
+	topP := flag.Float64("top-p", 0, "the nucleus sampling probability of the LLM backends, 0 for the model default")
+	nmtModel := flag.String("nmt-model", "nmt", "the Google Translate model: nmt or base")
+	apiVersion := flag.Int("api-version", 2, "the version of the Cloud Translation API used by the nmt backend: 2 or 3")
+	project := flag.String("project", os.Getenv("GOOGLE_CLOUD_PROJECT"), "the Google Cloud project of the v3 API")
+	proxy := flag.String("proxy", "", "the URL of the proxy used for all requests (default from HTTP_PROXY/HTTPS_PROXY/NO_PROXY)")
+	baseURL := flag.String("base-url", "", "the base URL of the OpenAI-compatible API (default "+translate.DefaultOpenAIURL+")")
+	concat := flag.Bool("append", cfg.Append, "append the translation")
+	prepend := flag.Bool("prepend", false, "like -append, but put the translation before the text")
+	separator := flag.String("separator", "", "the separator between the text and the translation with -append or -prepend, with Go escapes like \\n (default \\n---\\n)")
 	list := flag.Bool("list", false, "list all possible language codes")
+	refreshLangs := flag.Bool("refresh-langs", false, "fetch the supported languages again instead of using the cached ones")
+	listFilter := flag.String("list-filter", "", "only list the languages whose name or code contains this text")
+	selection := flag.String("selection", "auto", "the selection to use: clipboard, primary, or auto to read the primary selection when available and write the clipboard")
+	readSel := flag.String("read-selection", "", "the selection the text is read from, overriding -selection: clipboard or primary")
+	writeSel := flag.String("write-selection", "", "the selection the translation is written to, overriding -selection: clipboard or primary")
+	nativeClipboard := flag.Bool("native-clipboard", false, "fall back to pbpaste and pbcopy on macOS when the clipboard cannot be accessed")
+	splitSelections := flag.Bool("split-selections", false, "write the source to the primary selection and the translation to the clipboard")
+	offerHTML := flag.Bool("html-target", false, "also offer the source and the translation as text/html to the applications pasting rich text, on X11")
+	clipboardTool := flag.String("clipboard-tool", "", "access the clipboard with this tool instead of the default: pbcopy, wl-clipboard, xclip or xsel")
+	keyFile := flag.String("keyfile", "", "read the API key from this file instead of the environment")
+	credentials := flag.String("credentials", "", "authenticate the nmt backend with this service account file instead of an API key (default from GOOGLE_APPLICATION_CREDENTIALS)")
+	watch := flag.Bool("watch", false, "keep running and translate the selection whenever it changes")
+	interval := flag.Duration("interval", 500*time.Millisecond, "how often the selection is polled in watch mode")
+	debounce := flag.Duration("debounce", 400*time.Millisecond, "how long the selection must stay unchanged before it is translated in watch mode")
+	noCache := flag.Bool("no-cache", false, "do not use the translation cache")
+	wipeCache := flag.Bool("clear-cache", false, "remove all cached translations and exit")
+	cacheSize := flag.Int("cache-size", defaultCacheSize, "the maximum number of cached translations")
+	timeout := flag.Duration("timeout", translate.DefaultTimeout, "the maximum time to wait for each request, 0 for no limit")
+	prompt := flag.String("prompt", "", "the system instruction given to the LLM backends")
+	promptFile := flag.String("promptfile", "", "read the system instruction given to the LLM backends from this file")
+	retries := flag.Int("retries", translate.DefaultRetries, "the maximum number of attempts for requests failing with transient errors")
+	format := flag.String("format", "text", "the format of the text: text or markdown")
+	normalized := flag.Bool("normalize", true, "collapse repeated and non-breaking spaces before translating")
+	straightQuotes := flag.Bool("straight-quotes", false, "replace smart quotes with straight ones when normalizing")
+	noSkip := flag.Bool("no-skip", false, "translate the text even if it is a URL, an email address or a path")
+	source := flag.String("source", "", "the language of the text, skipping the detection")
+	threshold := flag.Float64("confidence-threshold", 0, "translate into the learned language when the detection confidence is below this value")
+	paraphrase := flag.Bool("paraphrase", false, "rewrite the text more clearly in its own language instead of translating it, with an LLM backend")
+	segment := flag.Bool("segment", false, "split mixed-language text by language and translate every part into its own target")
+	reverse := flag.Bool("reverse", false, "translate in the other direction: into the learned languages when the known one would be picked, and vice versa")
+	candidateList := flag.String("candidates", "", "comma-separated list of the only languages the text can be in, e.g. en,ko,es")
+	localDetect := flag.Bool("local-detect", false, "detect the language from the script of the text only, without requests")
+	noPostprocess := flag.Bool("no-postprocess", false, "do not clean up the translations with the rules of the target language")
+	ocrImage := flag.Bool("ocr", false, "when the clipboard holds an image, translate its text extracted with tesseract")
+	ocrLangs := flag.String("ocr-lang", "", "the tesseract languages of the images, such as kor+eng (default the tesseract default)")
+	pick := flag.Bool("pick", false, "choose the target language from the supported ones after the detection, with fzf if installed")
+	minCopyConfidence := flag.Float64("min-confidence", 0, "only show the translation, without copying it, when the detection confidence is below this value")
+	force := flag.Bool("force", false, "copy the translation even if the detection confidence is below -min-confidence, and translate selections longer than -max-chars")
+	maxChars := flag.Int("max-chars", 5000, "refuse to translate selections longer than this many characters, 0 for no limit")
+	detectOnly := flag.Bool("detect", false, "only detect the language of the text, without translating it")
+	ipa := flag.Bool("ipa", false, "add the IPA pronunciation when translating a single word, with an LLM backend")
+	romanized := flag.Bool("romanize", false, "add the romanization of Chinese, Japanese and Korean translations")
+	transliterate := flag.Bool("transliterate-only", false, "write the romanization of Chinese, Japanese and Korean text instead of translating it")
+	register := flag.String("register", translate.RegisterFormal, "the speech level of the LLM translations: formal or informal")
+	domain := flag.String("context", "", "the domain of the text given to the LLM backends, e.g. \"software documentation\" or \"casual chat\"")
+	glossaryFile := flag.String("glossary", "", "a file of source=target lines with forced term translations")
+	usage := flag.Bool("usage", false, "print the total usage of every backend and exit")
+	history := flag.Bool("history", false, fmt.Sprintf("print the last %d translations and exit", historyShown))
+	historyClear := flag.Bool("history-clear", false, "remove the translation history and exit")
+	unescape := flag.Bool("unescape", true, "decode the HTML entities of the Google Translate v2 translations, always off with -raw and -format markdown")
+	raw := flag.Bool("raw", false, "keep the HTML entities of the text, such as &amp; in code, as they are")
+	trim := flag.Bool("trim", true, "remove the whitespace and the quotes or code fence wrapping the answers of the LLM backends")
+	stream := flag.Bool("stream", false, "receive the Gemini response incrementally, logging it as it arrives")
+	quiet := flag.Bool("quiet", false, "write to stderr instead of showing notifications")
+	icon := flag.String("icon", "", "the notification icon (default the first of -icon-paths that exists)")
+	iconPaths := flag.String("icon-paths", "", "the icons tried in order for the notifications, separated by "+string(os.PathListSeparator)+" and relative to the XDG data directories unless absolute (default the locale icons of the common themes)")
+	appName := flag.String("appname", "TClip", "the application name shown in notifications")
+	notifyPreview := flag.Int("notify-preview", 0, "only show the first characters of the translations in the notifications, 0 to show them whole")
+	notifyTimeout := flag.Duration("notify-timeout", 0, "how long translations stay on screen with notify-send, 0 for the default of the daemon")
+	errorTimeout := flag.Duration("notify-error-timeout", 0, "how long errors stay on screen with notify-send (default -notify-timeout)")
+	urgency := flag.String("urgency", urgencyLow, "the urgency of the notifications: low, normal or critical; errors are always critical")
+	keep := flag.Bool("keep", false, "keep the original text in the clipboard and only show the translation")
+	dryRun := flag.Bool("dry-run", false, "translate and show the result without modifying the clipboard")
+	speakOut := flag.Bool("speak", false, "read the translation aloud with the text-to-speech program of the platform")
+	compare := flag.Bool("compare", false, "also translate with Google Translate, or with Gemini for the nmt backend, and show both translations")
+	verify := flag.Bool("verify", false, "translate the result back into the source language to check it")
+	inFile := flag.String("f", "", "translate the contents of this file and print the translation to stdout")
+	outFile := flag.String("o", "", "write the translation of -f or -stdin to this file instead of stdout")
+	chunkSize := flag.Int("chunk-size", defaultChunkSize, "the maximum number of bytes sent in a single request, 0 for no limit")
+	concurrency := flag.Int("concurrency", 4, "the number of chunks translated in parallel")
+	lines := flag.Bool("lines", false, "translate every line of -f or -stdin on its own, printed as the source and translation separated by a tab")
+	stdin := flag.Bool("stdin", false, "read the text from stdin and print the translation to stdout")
+	jsonOut := flag.Bool("json", false, "print the translation as JSON to stdout instead of showing a notification")
+	var verbose bool
+	flag.BoolVar(&verbose, "v", false, "log what tclip does, same as -log-level info")
+	flag.BoolVar(&verbose, "verbose", false, "same as -v")
+	logLevel := flag.String("log-level", "warn", "the minimum level of the logged messages: debug, info, warn or error")
+	benchRuns := flag.Int("bench", 0, "translate a sample text this many times with every backend, print the latency and exit")
+	doctor := flag.Bool("doctor", false, "check the API keys, clipboard and notifications, and exit")
+	showVersion := flag.Bool("version", false, "print the version and build information and exit")
 	flag.Parse()
 
-	notify := notificator.New(notificator.Options{
-		DefaultIcon: "/usr/share/icons/hicolor/scalable/apps/org.gnome.Settings-region-symbolic.svg",
-		AppName:     "TClip",
-	})
+	if err := setupLogging(*logLevel, verbose); err != nil {
+		return withCode(exitUsage, err)
+	}
+	if *showVersion {
+		fmt.Println(versionInfo())
+		return nil
+	}
 
-	if hasPrimary {
-		setPrimary(true)
+	readFrom, writeTo, err := resolveSelections(*selection, *readSel, *writeSel)
+	if err != nil {
+		return withCode(exitUsage, err)
+	}
+	if err := checkClipboardTool(*clipboardTool); err != nil {
+		return withCode(exitUsage, err)
+	}
+	if *splitSelections && !hasPrimary {
+		return withCode(exitUsage, errors.New("-split-selections needs the primary selection, which is not available on this platform"))
+	}
+	if *offerHTML && !hasPrimary {
+		return withCode(exitUsage, errors.New("-html-target needs X11, which is not available on this platform"))
+	} else if *offerHTML && *splitSelections {
+		return withCode(exitUsage, errors.New("-html-target can't be combined with -split-selections"))
+	} else if *offerHTML && *clipboardTool != "" {
+		return withCode(exitUsage, errors.New("-html-target can't be combined with -clipboard-tool, it talks to the X server itself"))
+	}
+	if *fallback != "" && *fallback != "nmt" {
+		return withCode(exitUsage, fmt.Errorf("unknown fallback %q, expected nmt", *fallback))
+	}
+	if err := checkUrgency(*urgency); err != nil {
+		return withCode(exitUsage, err)
+	}
+	if err := translate.CheckRegister(*register); err != nil {
+		return withCode(exitUsage, err)
+	}
+	if *format != "text" && *format != "markdown" {
+		return withCode(exitUsage, fmt.Errorf("unknown format %q", *format))
+	}
+	if *lines && *inFile == "" && !*stdin {
+		return withCode(exitUsage, errors.New("-lines needs -f or -stdin"))
+	}
+	sep := defaultSeparator
+	if *separator != "" {
+		if sep, err = strconv.Unquote(`"` + *separator + `"`); err != nil {
+			return withCode(exitUsage, fmt.Errorf("invalid separator %q: %w", *separator, err))
+		}
+	}
+	customPrompt := *prompt
+	if *promptFile != "" {
+		data, err := os.ReadFile(*promptFile)
+		if err != nil {
+			return err
+		}
+		customPrompt = string(data)
 	}
-	text, err := clipboard.ReadAll()
+	var terms translate.Glossary
+	if *glossaryFile != "" {
+		var err error
+		if terms, err = translate.LoadGlossary(*glossaryFile); err != nil {
+			return err
+		}
+	}
+	cacheFile, err := cachePath()
 	if err != nil {
-		notify.Push("Error reading the clipboard", err.Error(), "", notificator.UR_NORMAL)
-		return
+		return err
+	}
+	usageFile, err := dataPath("usage.jsonl")
+	if err != nil {
+		return err
+	}
+	keyState, err := dataPath("gemini-key")
+	if err != nil {
+		return err
+	}
+	if *usage {
+		if err := printUsage(usageFile); err != nil {
+			return err
+		}
+		return nil
+	}
+	historyFile, err := dataPath("history.jsonl")
+	if err != nil {
+		return err
+	}
+	if *history {
+		if err := printHistory(historyFile, historyShown); err != nil {
+			return err
+		}
+		return nil
+	}
+	if *historyClear {
+		if err := removeFile(historyFile); err != nil {
+			return err
+		}
+		return nil
+	}
+	if *wipeCache {
+		if err := removeFile(cacheFile); err != nil {
+			return err
+		}
+		return nil
 	}
 
-	if text == "" {
-		notify.Push("Error", "No text selected", "", notificator.UR_NORMAL)
-		return
+	if *icon == "" {
+		paths := iconCandidates
+		if *iconPaths != "" {
+			paths = filepath.SplitList(*iconPaths)
+		}
+		*icon = findIcon(paths)
+		slog.Debug("using the notification icon", "icon", *icon)
+	}
+	notify := newNotifier(*appName, *icon)
+	if notify.unavailable != "" && !*quiet && !*doctor && *benchRuns == 0 && !*jsonOut && *inFile == "" && !*stdin {
+		slog.Warn("notifications are unavailable, writing them to stderr", "reason", notify.unavailable)
 	}
-	log.Println("selected text:", text)
 
-	gTrans, err := createClientWithKey(*useLLM)
-	if err != nil {
+	if *backend == "" {
 		if *useLLM {
-			log.Fatalf("%v\nMake sure you have set the GOOGLE_TRANSLATE_APIKEY environment variable", err)
+			*backend = "gemini"
 		} else {
-			log.Fatalf("%v\nMake sure you have set the GEMINI_APIKEY environment variable", err)
+			*backend = "nmt"
 		}
 	}
-	defer gTrans.close()
+	if *domain != "" && (*backend == "nmt" || *backend == "exec") {
+		slog.Warn("-context is ignored by the " + *backend + " backend")
+	}
+	if *register != translate.RegisterFormal && (*backend == "nmt" || *backend == "exec") {
+		slog.Warn("-register is ignored by the " + *backend + " backend")
+	}
+	if *paraphrase && (*backend == "nmt" || *backend == "exec") {
+		return withCode(exitUsage, translate.ErrNoParaphrase)
+	}
+	if *doctor {
+		a := &app{notify: notify, nativeClipboard: *nativeClipboard, clipboardTool: *clipboardTool}
+		return a.doctor(*backend, *keyFile)
+	}
+	learns := strings.Split(*learn, ",")
+	var candidates []string
+	if *candidateList != "" {
+		candidates = strings.Split(*candidateList, ",")
+	}
+	var cache *translate.Cache
+	// the benchmark must reach the backends every time
+	if !*noCache && *benchRuns == 0 {
+		if cache, err = translate.LoadCache(cacheFile, *cacheSize); err != nil {
+			slog.Warn("unable to load the cache", "err", err)
+		}
+	}
+	if *timeout == 0 {
+		// the client takes a zero timeout for its default one
+		*timeout = -1
+	}
+	gTrans, err := translate.NewClient(translate.Options{
+		Backend:      *backend,
+		Model:        *model,
+		BaseURL:      *baseURL,
+		Proxy:        *proxy,
+		NMTModel:     *nmtModel,
+		APIVersion:   *apiVersion,
+		Project:      *project,
+		Temperature:  float32(*temperature),
+		TopP:         float32(*topP),
+		KeyFile:      *keyFile,
+		Credentials:  *credentials,
+		Format:       *format,
+		Known:        *known,
+		Learn:        learns,
+		CustomPrompt: customPrompt,
+		Glossary:     terms,
+		Domain:       *domain,
+		Register:     *register,
+		Fallback:     *fallback,
+		Timeout:      *timeout,
+		Retries:      *retries,
+		KeyState:     keyState,
+		ExecCmd:      *execCmd,
+		Source:       *source,
+		Stream:       *stream,
+		NoTrim:       !*trim,
+		KeepEntities: !unescapeEntities(*unescape, *raw, *format),
+		OnUsage:      recordUsage(usageFile),
+		Cache:        cache,
+		LangsDir:     filepath.Dir(cacheFile),
+		RefreshLangs: *refreshLangs,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, translate.ErrNoExecCmd):
+			return withCode(exitUsage, err)
+		case *backend == "gemini":
+			return fmt.Errorf("%w\nMake sure you have set the GEMINI_APIKEY environment variable or passed -keyfile", err)
+		case *backend == "nmt":
+			return fmt.Errorf("%w\nMake sure you have set the GOOGLE_TRANSLATE_APIKEY environment variable or passed -keyfile or -credentials", err)
+		case *backend == "openai":
+			return fmt.Errorf("%w\nMake sure you have set the OPENAI_APIKEY environment variable or passed -keyfile", err)
+		case *backend == "anthropic":
+			return fmt.Errorf("%w\nMake sure you have set the ANTHROPIC_APIKEY environment variable or passed -keyfile", err)
+		default:
+			return err
+		}
+	}
+	defer gTrans.Close()
 
-	if *list {
-		gTrans.SupportedLanguages(*known)
-		return
+	if *benchRuns > 0 {
+		target := learns[0]
+		if translate.SameLanguage(target, "en") {
+			target = *known
+		}
+		backends := []benchBackend{{*backend, gTrans}}
+		if alt, name := gTrans.Comparison(); alt != nil {
+			backends = append(backends, benchBackend{name, alt})
+		}
+		bench(os.Stdout, backends, target, *benchRuns)
+		return nil
 	}
 
-	var trans = ""
-	var det = ""
-	if gTrans.useLLM() {
-		trans, err = gTrans.translate(*known, text)
+	if *list {
+		langs, err := gTrans.SupportedLanguages(*known)
 		if err != nil {
-			notify.Push("Error", "Unable to translate the language", "", notificator.UR_NORMAL)
-			log.Fatal(err)
+			return err
 		}
-		det = "with LLM"
-	} else {
-		det, err := gTrans.detect(text)
-		if err != nil {
-			notify.Push("Error", "Unable to detect the language", "", notificator.UR_NORMAL)
-			log.Fatal(err)
+		if err := printLanguages(langs, *listFilter, *jsonOut); err != nil {
+			return err
 		}
-		log.Println("detected language:", det)
+		return nil
+	}
 
-		if det == *known {
-			trans, err = gTrans.translate(*learn, text)
-			if err != nil {
-				notify.Push("Error", "Unable to translate the language", "", notificator.UR_NORMAL)
-				log.Fatal(err)
-			}
+	a := &app{
+		tr:                gTrans,
+		notify:            notify,
+		urgency:           *urgency,
+		notifyTimeout:     *notifyTimeout,
+		notifyPreview:     *notifyPreview,
+		errorTimeout:      cmp.Or(*errorTimeout, *notifyTimeout),
+		speak:             *speakOut,
+		noSkip:            *noSkip,
+		localDetect:       *localDetect,
+		candidates:        candidates,
+		reverse:           *reverse,
+		segment:           *segment,
+		paraphrase:        *paraphrase,
+		transliterate:     *transliterate,
+		pick:              *pick,
+		ocrLangs:          *ocrLangs,
+		minCopyConfidence: *minCopyConfidence,
+		force:             *force,
+		maxChars:          *maxChars,
+		noPostprocess:     *noPostprocess,
+		known:             *known,
+		learn:             learns,
+		backend:           *backend,
+		format:            *format,
+		verify:            *verify,
+		source:            *source,
+		normalized:        *normalized,
+		straightQuotes:    *straightQuotes,
+		minConfidence:     *threshold,
+		romanized:         *romanized,
+		ipa:               *ipa,
+		historyFile:       historyFile,
+		concat:            *concat || *prepend,
+		prepend:           *prepend,
+		separator:         sep,
+		json:              *jsonOut,
+		quiet:             *quiet,
+		chunkSize:         *chunkSize,
+		concurrency:       *concurrency,
+		dryRun:            *dryRun,
+		keep:              *keep,
+		readFrom:          readFrom,
+		writeTo:           writeTo,
+		nativeClipboard:   *nativeClipboard,
+		clipboardTool:     *clipboardTool,
+		splitSelections:   *splitSelections,
+		htmlTarget:        *offerHTML,
+	}
+	if *compare {
+		if a.compare, a.compareBackend = gTrans.Comparison(); a.compare == nil {
+			slog.Warn("-compare needs both GOOGLE_TRANSLATE_APIKEY and GEMINI_APIKEY, showing only one backend", "backend", *backend)
+		}
+	}
+	if *watch {
+		a.watch(*interval, *debounce)
+		return nil
+	}
+
+	var text string
+	if *inFile != "" || *stdin {
+		var data []byte
+		if *inFile != "" {
+			data, err = os.ReadFile(*inFile)
 		} else {
-			trans, err = gTrans.translate(*known, text)
+			data, err = io.ReadAll(os.Stdin)
+		}
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			return errNoText
+		}
+		text = string(data)
+		a.out = os.Stdout
+		if *outFile != "" {
+			f, err := os.Create(*outFile)
 			if err != nil {
-				notify.Push("Error", "Unable to translate the language", "", notificator.UR_NORMAL)
-				log.Fatal(err)
+				return err
 			}
+			defer func() {
+				if cerr := f.Close(); err == nil {
+					err = cerr
+				}
+			}()
+			a.out = f
+		}
+	} else {
+		if *ocrImage {
+			text, err = a.readImageText()
+			a.fromImage = err == nil
+		}
+		if !*ocrImage || errors.Is(err, errNoImage) {
+			text, err = a.readText()
+		}
+		if errors.Is(err, errNoText) {
+			a.pushError("Error", "No text selected")
+			return err
+		} else if err != nil {
+			a.pushError("Error reading the clipboard", err.Error())
+			return withCode(exitClipboard, err)
+		}
+		if err := a.checkLength(text); err != nil {
+			return err
 		}
-		det = "from " + det
-	}
-	log.Println("translated text:", trans)
-	if hasPrimary {
-		setPrimary(false)
-	}
-	if *concat {
-		trans = text + "\n---\n" + trans
 	}
-	if err := clipboard.WriteAll(trans); err != nil {
-		log.Fatal(err)
+	if *detectOnly {
+		err = a.detect(text)
+	} else if *lines {
+		err = a.runLines(text)
+	} else {
+		_, err = a.run(text)
 	}
-	notify.Push(fmt.Sprintf("Translating %s: %s", det, text), trans, "", notificator.UR_NORMAL)
+	return err
 }
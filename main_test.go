@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// writeHTMLSelection starts the test binary with the subcommand serving the selection
+	if len(os.Args) > 1 && os.Args[1] == serveSelectionCmd {
+		if err := serveSelection(os.Args[2:]); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// fakeTranslator translates into upper case, or fails with err when set, recording the texts it was given
+type fakeTranslator struct {
+	err   error
+	mu    sync.Mutex
+	texts []string
+}
+
+func (f *fakeTranslator) Translate(target, text string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.texts = append(f.texts, text)
+	if f.err != nil {
+		return "", f.err
+	}
+	return strings.ToUpper(text), nil
+}
+
+func (f *fakeTranslator) Detect(text string) (string, float64, error) {
+	return "es", 1, nil
+}
+
+func TestStripAppended(t *testing.T) {
+	tests := []struct {
+		concat, prepend bool
+		separator, text string
+		want            string
+	}{
+		{true, false, defaultSeparator, "hola\n---\nHELLO", "hola"},
+		{true, false, defaultSeparator, "hola\n---\nHELLO\n---\nOTHER", "hola"},
+		{true, true, defaultSeparator, "HELLO\n---\nhola", "hola"},
+		{true, true, defaultSeparator, "OTHER\n---\nHELLO\n---\nhola", "hola"},
+		{true, false, defaultSeparator, "hola", "hola"},
+		{true, false, defaultSeparator, "\n---\nHELLO", "\n---\nHELLO"},
+		{true, true, defaultSeparator, "HELLO\n---\n ", "HELLO\n---\n "},
+		{true, false, "", "hola\n---\nHELLO", "hola\n---\nHELLO"},
+		{false, false, defaultSeparator, "hola\n---\nHELLO", "hola\n---\nHELLO"},
+		{true, false, " | ", "hola | HELLO", "hola"},
+	}
+	for _, tt := range tests {
+		a := &app{concat: tt.concat, prepend: tt.prepend, separator: tt.separator}
+		if got := a.stripAppended(tt.text); got != tt.want {
+			t.Errorf("stripAppended(%q) with prepend %t = %q, want %q", tt.text, tt.prepend, got, tt.want)
+		}
+	}
+}
+
+func TestRunAppendTwice(t *testing.T) {
+	for _, prepend := range []bool{false, true} {
+		path := fakeClipboard(t, "hola")
+		a := newSelectionApp(&fakeTranslator{})
+		a.concat, a.prepend, a.separator = true, prepend, defaultSeparator
+		want := "hola" + defaultSeparator + "HOLA"
+		if prepend {
+			want = "HOLA" + defaultSeparator + "hola"
+		}
+		for range 2 {
+			text, err := a.readText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := a.run(text); err != nil {
+				t.Fatal(err)
+			}
+			if got := readClipboardFile(t, path); got != want {
+				t.Errorf("clipboard with prepend %t = %q, want %q", prepend, got, want)
+			}
+		}
+	}
+}
+
+func TestUnescapeEntities(t *testing.T) {
+	tests := []struct {
+		unescape, raw bool
+		format        string
+		want          bool
+	}{
+		{true, false, "text", true},
+		{false, false, "text", false},
+		{true, true, "text", false},
+		{false, true, "text", false},
+		{true, false, "markdown", false},
+	}
+	for _, tt := range tests {
+		if got := unescapeEntities(tt.unescape, tt.raw, tt.format); got != tt.want {
+			t.Errorf("unescapeEntities(%t, %t, %q) = %t, want %t", tt.unescape, tt.raw, tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// segment is a piece of markdown text, either prose or a fenced code block
+type segment struct {
+	text string
+	code bool
+}
+
+// splitFences splits markdown text into prose and fenced code block segments,
+// which concatenated give back the original text
+func splitFences(text string) []segment {
+	var segments []segment
+	var cur strings.Builder
+	code := false
+	flush := func() {
+		if cur.Len() > 0 {
+			segments = append(segments, segment{text: cur.String(), code: code})
+			cur.Reset()
+		}
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		fence := strings.HasPrefix(strings.TrimLeft(line, " \t"), "```")
+		if fence && !code {
+			flush()
+			code = true
+			cur.WriteString(line)
+		} else if fence && code {
+			cur.WriteString(line)
+			flush()
+			code = false
+		} else {
+			cur.WriteString(line)
+		}
+	}
+	flush()
+	return segments
+}
+
+// translateSegment translates text into target, leaving fenced code blocks untouched in markdown
+func (a *app) translateSegment(target, text string) (string, error) {
+	if a.format != "markdown" {
+		return a.tr.Translate(target, text)
+	}
+	var out strings.Builder
+	for _, seg := range splitFences(text) {
+		if seg.code {
+			out.WriteString(seg.text)
+			continue
+		}
+		trans, err := keepSpace(seg.text, func(core string) (string, error) {
+			return a.tr.Translate(target, core)
+		})
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(trans)
+	}
+	return out.String(), nil
+}
+
+// keepSpace calls fn with text stripped of its surrounding whitespace,
+// and puts that whitespace back around the result; whitespace-only text is returned as is
+func keepSpace(text string, fn func(string) (string, error)) (string, error) {
+	leading, core, trailing := splitSpace(text)
+	if core == "" {
+		return text, nil
+	}
+	res, err := fn(core)
+	if err != nil {
+		return "", err
+	}
+	return leading + res + trailing, nil
+}
+
+// splitSpace splits text into its leading whitespace, the rest, and its trailing whitespace;
+// whitespace-only text is all leading
+func splitSpace(text string) (leading, core, trailing string) {
+	core = strings.TrimLeftFunc(text, unicode.IsSpace)
+	leading = text[:len(text)-len(core)]
+	core = strings.TrimRightFunc(core, unicode.IsSpace)
+	trailing = text[len(leading)+len(core):]
+	return leading, core, trailing
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const markdownText = "# Título\n\nUn `código` en línea.\n\n  ```go\n  fmt.Println(\"hola\")  \n\n  ```\nFin del texto.\n```\nsin cerrar\n"
+
+func TestSplitFences(t *testing.T) {
+	segments := splitFences(markdownText)
+	want := []segment{
+		{"# Título\n\nUn `código` en línea.\n\n", false},
+		{"  ```go\n  fmt.Println(\"hola\")  \n\n  ```\n", true},
+		{"Fin del texto.\n", false},
+		{"```\nsin cerrar\n", true},
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("splitFences = %+v, want %+v", segments, want)
+	}
+	var sb strings.Builder
+	for i, seg := range segments {
+		if seg != want[i] {
+			t.Errorf("segment %d = %+v, want %+v", i, seg, want[i])
+		}
+		sb.WriteString(seg.text)
+	}
+	if sb.String() != markdownText {
+		t.Errorf("the segments give back %q, want %q", sb.String(), markdownText)
+	}
+}
+
+func TestTranslateSegmentKeepsCode(t *testing.T) {
+	tr := &fakeTranslator{}
+	a := &app{tr: tr, format: "markdown"}
+	got, err := a.translateSegment("en", markdownText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# TÍTULO\n\nUN `CÓDIGO` EN LÍNEA.\n\n  ```go\n  fmt.Println(\"hola\")  \n\n  ```\nFIN DEL TEXTO.\n```\nsin cerrar\n"
+	if got != want {
+		t.Errorf("translateSegment = %q, want %q", got, want)
+	}
+	for _, text := range tr.texts {
+		if strings.Contains(text, "```") {
+			t.Errorf("the backend got the code block %q", text)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build darwin
+
+package main
+
+// fallbackTool returns the clipboard tool used when the library fails: pbcopy, with -native-clipboard
+func fallbackTool(native bool) string {
+	if native {
+		return "pbcopy"
+	}
+	return ""
+}
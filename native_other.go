@@ -0,0 +1,13 @@
+//go:build !darwin
+
+package main
+
+import "os"
+
+// fallbackTool returns the clipboard tool used when the library fails: wl-clipboard on Wayland
+func fallbackTool(native bool) string {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return "wl-clipboard"
+	}
+	return ""
+}
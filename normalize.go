@@ -0,0 +1,49 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	horizontalSpace = regexp.MustCompile(`[\t\f\v \x{00A0}\x{2000}-\x{200A}\x{202F}\x{205F}\x{3000}]+`)
+	blankLines      = regexp.MustCompile(`\n{3,}`)
+	smartQuotes     = strings.NewReplacer("‘", "'", "’", "'", "‚", "'", "‛", "'", "“", `"`, "”", `"`, "„", `"`, "‟", `"`)
+)
+
+// normalize cleans up text before translation: non-breaking and repeated spaces become a single
+// space, trailing spaces and extra blank lines are removed, and smart quotes are optionally straightened.
+// The whitespace around the text and the indentation of every line are kept, and so are fenced code
+// blocks in markdown.
+func (a *app) normalize(text string) string {
+	if !a.normalized {
+		return text
+	}
+	leading, text, trailing := splitSpace(text)
+	if a.format != "markdown" {
+		return leading + a.normalizeProse(text) + trailing
+	}
+	var sb strings.Builder
+	for _, seg := range splitFences(text) {
+		if seg.code {
+			sb.WriteString(seg.text)
+		} else {
+			sb.WriteString(a.normalizeProse(seg.text))
+		}
+	}
+	return leading + sb.String() + trailing
+}
+
+func (a *app) normalizeProse(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		lines[i] = indent + strings.TrimRight(horizontalSpace.ReplaceAllString(line[len(indent):], " "), " ")
+	}
+	text = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	if a.straightQuotes {
+		text = smartQuotes.Replace(text)
+	}
+	return text
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		format, text, want string
+	}{
+		{"text", "  hola  ", "  hola  "},
+		{"text", "  hola   mundo  \n", "  hola mundo  \n"},
+		{"text", "\n\thola  \nmundo\n\n\n\nadiós \n\n", "\n\thola\nmundo\n\nadiós \n\n"},
+		{"markdown", "  hola  mundo\n```\na  b  \n```\n", "  hola mundo\n```\na  b  \n```\n"},
+		{"text", " \n ", " \n "},
+	}
+	for _, tt := range tests {
+		a := &app{normalized: true, format: tt.format}
+		if got := a.normalize(tt.text); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTranslateKeepsSpace(t *testing.T) {
+	tr := &fakeTranslator{}
+	a := &app{tr: tr, normalized: true, format: "text"}
+	got, err := a.translate("en", a.normalize("  hola  "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "  HOLA  " {
+		t.Errorf("translate = %q, want %q", got, "  HOLA  ")
+	}
+	if len(tr.texts) != 1 || tr.texts[0] != "hola" {
+		t.Errorf("the backend got %q, want only the text without its whitespace", tr.texts)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/0xAX/notificator"
+)
+
+// the notification urgencies accepted by -urgency
+const (
+	urgencyLow      = "low"
+	urgencyNormal   = "normal"
+	urgencyCritical = "critical"
+)
+
+// checkUrgency validates the value of -urgency
+func checkUrgency(urgency string) error {
+	switch urgency {
+	case urgencyLow, urgencyNormal, urgencyCritical:
+		return nil
+	}
+	return fmt.Errorf("unknown urgency %q, expected low, normal or critical", urgency)
+}
+
+// notifier shows desktop notifications, calling notify-send directly when available
+// since the notificator library knows neither the low urgency nor sounds
+type notifier struct {
+	lib        *notificator.Notificator
+	appName    string
+	icon       string
+	notifySend string
+	// unavailable tells why notifications can't be shown, empty when they can
+	unavailable string
+}
+
+func newNotifier(appName, icon string) *notifier {
+	n := &notifier{
+		lib:     notificator.New(notificator.Options{DefaultIcon: icon, AppName: appName}),
+		appName: appName,
+		icon:    icon,
+	}
+	switch runtime.GOOS {
+	case "darwin":
+	case "windows":
+		// the library relies on Growl for Windows
+		if _, err := exec.LookPath("growlnotify"); err != nil {
+			n.unavailable = "growlnotify is not installed"
+		}
+	default:
+		n.notifySend, _ = exec.LookPath("notify-send")
+		if n.notifySend == "" {
+			n.unavailable = "notify-send is not installed"
+		} else if !hasSessionBus() {
+			n.unavailable = "there is no D-Bus session bus to reach a notification daemon"
+		}
+	}
+	return n
+}
+
+// hasSessionBus reports whether the D-Bus session bus used by notify-send can be found
+func hasSessionBus() bool {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+		return true
+	}
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(dir, "bus"))
+	return err == nil
+}
+
+// push shows a notification with the given urgency; critical ones play the error sound where supported.
+// With notify-send, the notification expires after expire, or when the daemon decides if it is zero
+func (n *notifier) push(title, text, urgency string, expire time.Duration) error {
+	if n.notifySend == "" {
+		if urgency == urgencyCritical {
+			return n.lib.Push(title, text, "", notificator.UR_CRITICAL)
+		}
+		return n.lib.Push(title, text, "", notificator.UR_NORMAL)
+	}
+	args := []string{"-a", n.appName, "-u", urgency}
+	if n.icon != "" {
+		args = append(args, "-i", n.icon)
+	}
+	if urgency == urgencyCritical {
+		args = append(args, "-h", "string:sound-name:dialog-error")
+	}
+	if expire > 0 {
+		args = append(args, "-t", strconv.FormatInt(expire.Milliseconds(), 10))
+	}
+	return exec.Command(n.notifySend, append(args, "--", title, text)...).Run()
+}
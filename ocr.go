@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// errNoImage is returned by clipboardImage when the clipboard holds no image
+var errNoImage = errors.New("the clipboard holds no image")
+
+// clipboardImage returns the PNG image held by the clipboard, read with wl-paste on Wayland,
+// xclip on X11 and pngpaste on macOS
+func clipboardImage() ([]byte, error) {
+	var types, paste []string
+	switch {
+	case runtime.GOOS == "darwin":
+		// pngpaste fails when the clipboard holds no image
+		out, err := exec.Command("pngpaste", "-").Output()
+		if err != nil {
+			if errors.Is(err, exec.ErrNotFound) {
+				return nil, err
+			}
+			return nil, errNoImage
+		}
+		return out, nil
+	case os.Getenv("WAYLAND_DISPLAY") != "":
+		types, paste = []string{"wl-paste", "--list-types"}, []string{"wl-paste", "--type", "image/png"}
+	default:
+		types = []string{"xclip", "-selection", "clipboard", "-target", "TARGETS", "-out"}
+		paste = []string{"xclip", "-selection", "clipboard", "-target", "image/png", "-out"}
+	}
+	out, err := exec.Command(types[0], types[1:]...).Output()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(string(out), "image/png") {
+		return nil, errNoImage
+	}
+	return exec.Command(paste[0], paste[1:]...).Output()
+}
+
+// ocr extracts the text of a PNG image with tesseract, in the tesseract languages given by
+// langs, such as kor+eng, or its default language when empty
+func ocr(img []byte, langs string) (string, error) {
+	args := []string{"stdin", "stdout"}
+	if langs != "" {
+		args = append(args, "-l", langs)
+	}
+	cmd := exec.Command("tesseract", args...)
+	cmd.Stdin = bytes.NewReader(img)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("tesseract: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// readImageText returns the text of the image in the clipboard, failing with errNoImage if there is none
+func (a *app) readImageText() (string, error) {
+	img, err := clipboardImage()
+	if err != nil {
+		return "", err
+	}
+	text, err := ocr(img, a.ocrLangs)
+	if err != nil {
+		return "", err
+	}
+	slog.Info("extracted the text of the clipboard image", "text", text)
+	if strings.TrimSpace(text) == "" {
+		return "", errNoText
+	}
+	return text, nil
+}
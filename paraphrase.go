@@ -0,0 +1,34 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// paraphraser is implemented by the translators able to rewrite text in its own language
+type paraphraser interface {
+	Paraphrase(text string) (string, error)
+}
+
+// runParaphrase rewrites text in its own language instead of translating it, writing the result
+// to the clipboard like run
+func (a *app) runParaphrase(original, text string) (string, error) {
+	p, ok := a.tr.(paraphraser)
+	if !ok {
+		return "", withCode(exitUsage, translate.ErrNoParaphrase)
+	}
+	trans, err := p.Paraphrase(text)
+	if err != nil {
+		a.pushError("Error", "Unable to paraphrase the text")
+		return "", withCode(exitAPI, err)
+	}
+	slog.Info("paraphrased text", "text", trans)
+	res := result{Source: original, Translation: trans, Backend: a.backend}
+	if err := a.deliver(res, trans, trans, !a.keep); err != nil {
+		return "", err
+	}
+	a.push("Paraphrasing: "+original, trans)
+	a.record(res)
+	return trans, nil
+}
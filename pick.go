@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// languageLister is implemented by the translators that can list the languages they support
+type languageLister interface {
+	SupportedLanguages(targetLang string) ([]translate.Language, error)
+}
+
+var errNoPick = errors.New("no target language picked")
+
+// pickTarget lets the user choose the target language among the supported ones, with fzf
+// when it is installed or a numbered list on the terminal otherwise
+func (a *app) pickTarget() (string, error) {
+	lister, ok := a.tr.(languageLister)
+	if !ok {
+		return "", errors.New("the backend cannot list its languages")
+	}
+	langs, err := lister.SupportedLanguages(a.known)
+	if err != nil {
+		return "", err
+	}
+	if len(langs) == 0 {
+		return "", errors.New("the backend supports no languages")
+	}
+	if _, err := exec.LookPath("fzf"); err == nil {
+		return pickFzf(langs)
+	}
+	return pickPrompt(langs)
+}
+
+// pickFzf lets the user choose a language with fzf, which draws on the terminal by itself
+func pickFzf(langs []translate.Language) (string, error) {
+	var list strings.Builder
+	for _, l := range langs {
+		fmt.Fprintf(&list, "%s\t%s\n", l.Tag, l.Name)
+	}
+	cmd := exec.Command("fzf", "--prompt", "Translate into: ", "--delimiter", "\t", "--with-nth", "2,1")
+	cmd.Stdin = strings.NewReader(list.String())
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// fzf exits with 130 when the user cancels
+		return "", errNoPick
+	}
+	code, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\t")
+	return code, nil
+}
+
+// pickPrompt prints the languages on stderr and reads a number or a code from the terminal,
+// which is not stdin when the text comes from there
+func pickPrompt(langs []translate.Language) (string, error) {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		tty = os.Stdin
+	} else {
+		defer tty.Close()
+	}
+	for i, l := range langs {
+		fmt.Fprintf(os.Stderr, "%3d - %s: %s\n", i, l.Tag, l.Name)
+	}
+	fmt.Fprint(os.Stderr, "Translate into (number or code): ")
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	choice := strings.TrimSpace(line)
+	if choice == "" {
+		if err != nil {
+			return "", err
+		}
+		return "", errNoPick
+	}
+	if i, err := strconv.Atoi(choice); err == nil {
+		if i < 0 || i >= len(langs) {
+			return "", fmt.Errorf("no language numbered %d", i)
+		}
+		return langs[i].Tag.String(), nil
+	}
+	for _, l := range langs {
+		if strings.EqualFold(l.Tag.String(), choice) {
+			return l.Tag.String(), nil
+		}
+	}
+	return "", fmt.Errorf("unsupported language %q", choice)
+}
@@ -0,0 +1,87 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	anthropicURL       = "https://api.anthropic.com/v1/messages"
+	anthropicVersion   = "2023-06-01"
+	anthropicMaxTokens = 4096
+)
+
+type anthropicRequest struct {
+	Model       string          `json:"model"`
+	MaxTokens   int             `json:"max_tokens"`
+	System      string          `json:"system"`
+	Messages    []openaiMessage `json:"messages"`
+	Temperature float32         `json:"temperature"`
+	TopP        float32         `json:"top_p,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int32 `json:"input_tokens"`
+		OutputTokens int32 `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicGenerate returns the response of the messages API and the number of tokens used
+func (gt *Client) anthropicGenerate(ctx context.Context, system, text string) (string, int32, error) {
+	body, err := json.Marshal(anthropicRequest{
+		Model:       gt.model,
+		MaxTokens:   anthropicMaxTokens,
+		System:      system,
+		Messages:    []openaiMessage{{Role: "user", Content: text}},
+		Temperature: gt.temperature,
+		TopP:        gt.topP,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, anthropicURL, bytes.NewReader(body))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", gt.anthropicKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	resp, err := gt.httpClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	var out anthropicResponse
+	if resp.StatusCode != http.StatusOK {
+		// the body of an error is not always JSON, such as the pages of a proxy
+		json.NewDecoder(resp.Body).Decode(&out)
+		msg := ""
+		if out.Error != nil {
+			msg = out.Error.Type + ": " + out.Error.Message
+		}
+		return "", 0, newStatusError(resp, msg)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", 0, err
+	}
+	var sb strings.Builder
+	for _, c := range out.Content {
+		if c.Type == "text" {
+			sb.WriteString(c.Text)
+		}
+	}
+	return sb.String(), out.Usage.InputTokens + out.Usage.OutputTokens, nil
+}
@@ -0,0 +1,101 @@
+package translate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// cacheKey identifies a translation: the text, the target and everything changing the output
+type cacheKey struct {
+	Backend string `json:"backend"`
+	// Model is the model of the backend, or the command of the exec backend
+	Model string `json:"model,omitempty"`
+	// Settings is the hash of the other options changing the output, such as the prompt
+	Settings string `json:"settings,omitempty"`
+	Text     string `json:"text"`
+	Target   string `json:"target"`
+}
+
+// settingsOf hashes the options of opts that change the translations, other than the backend
+// and the model; the prompt covers the register, the domain, the glossary and the format
+func settingsOf(opts Options) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %q %d %q %g %g %t %t %q", opts.prompt(), opts.Format, opts.APIVersion, opts.Project,
+		opts.Temperature, opts.TopP, !opts.NoTrim, !opts.KeepEntities, opts.Source)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+type cacheEntry struct {
+	cacheKey
+	Translation string `json:"translation"`
+}
+
+// Cache is an on-disk LRU cache of translations, the most recently used entry last
+type Cache struct {
+	mu      sync.Mutex
+	path    string
+	max     int
+	entries []cacheEntry
+}
+
+// LoadCache reads the cache at path, starting empty if it doesn't exist yet
+func LoadCache(path string, max int) (*Cache, error) {
+	c := &Cache{path: path, max: max}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Cache) get(key cacheKey) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, e := range c.entries {
+		if e.cacheKey == key {
+			c.entries = append(append(c.entries[:i:i], c.entries[i+1:]...), e)
+			return e.Translation, true
+		}
+	}
+	return "", false
+}
+
+func (c *Cache) put(key cacheKey, translation string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, e := range c.entries {
+		if e.cacheKey == key {
+			c.entries = append(c.entries[:i], c.entries[i+1:]...)
+			break
+		}
+	}
+	c.entries = append(c.entries, cacheEntry{cacheKey: key, Translation: translation})
+	if c.max > 0 && len(c.entries) > c.max {
+		c.entries = c.entries[len(c.entries)-c.max:]
+	}
+}
+
+func (c *Cache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0o644)
+}
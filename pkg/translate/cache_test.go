@@ -0,0 +1,76 @@
+package translate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newOllamaClient returns a client of opts talking to a fake Ollama server answering with respond
+func newOllamaClient(t *testing.T, opts Options, respond func(w http.ResponseWriter, req ollamaRequest)) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding the request: %v", err)
+		}
+		respond(w, req)
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("OLLAMA_HOST", srv.URL)
+	// the nmt client is left out
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("GOOGLE_TRANSLATE_APIKEY", "")
+	opts.Backend = "ollama"
+	gt, err := NewClient(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(gt.Close)
+	return gt
+}
+
+func TestCacheKeyedBySettings(t *testing.T) {
+	cache, err := LoadCache(filepath.Join(t.TempDir(), "cache.json"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := 0
+	respond := func(w http.ResponseWriter, req ollamaRequest) {
+		requests++
+		answer := req.Model + ": 안녕하세요"
+		if strings.Contains(req.System, "informal") {
+			answer = req.Model + ": 안녕"
+		}
+		json.NewEncoder(w).Encode(ollamaResponse{Response: answer})
+	}
+	tests := []struct {
+		opts     Options
+		want     string
+		requests int
+	}{
+		{Options{Model: "llama3", Register: RegisterFormal}, "llama3: 안녕하세요", 1},
+		{Options{Model: "llama3", Register: RegisterInformal}, "llama3: 안녕", 2},
+		{Options{Model: "llama3", Register: RegisterFormal}, "llama3: 안녕하세요", 2},
+		{Options{Model: "mistral", Register: RegisterFormal}, "mistral: 안녕하세요", 3},
+		{Options{Model: "llama3", Register: RegisterFormal, Domain: "a chat"}, "llama3: 안녕하세요", 4},
+		{Options{Model: "llama3", Register: RegisterFormal, Glossary: Glossary{{"hello", "여보세요"}}}, "llama3: 안녕하세요", 5},
+		{Options{Model: "llama3", Register: RegisterFormal, Format: "markdown"}, "llama3: 안녕하세요", 6},
+		{Options{Model: "llama3", Register: RegisterFormal, KeepEntities: true}, "llama3: 안녕하세요", 7},
+		{Options{Model: "llama3", Register: RegisterFormal, Domain: "a chat"}, "llama3: 안녕하세요", 7},
+	}
+	for i, tt := range tests {
+		tt.opts.Known, tt.opts.Learn, tt.opts.Cache = "en", []string{"ko"}, cache
+		gt := newOllamaClient(t, tt.opts, respond)
+		got, err := gt.Translate("ko", "hello")
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if got != tt.want || requests != tt.requests {
+			t.Errorf("%d: got %q after %d requests, want %q after %d", i, got, requests, tt.want, tt.requests)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package translate
+
+import "context"
+
+// backendTranslator translates with one of the backends of gt other than the chosen one
+type backendTranslator struct {
+	gt      *Client
+	backend string
+}
+
+func (b backendTranslator) Translate(targetLang, text string) (string, error) {
+	return b.gt.translateWith(b.backend, targetLang, text)
+}
+
+func (b backendTranslator) Detect(text string) (string, float64, error) {
+	return b.gt.Detect(text)
+}
+
+func (b backendTranslator) WithContext(ctx context.Context) Translator {
+	return backendTranslator{b.gt.withContext(ctx), b.backend}
+}
+
+// Comparison returns a translator for the backend to compare the chosen one with, Google
+// Translate for the LLMs and Gemini for Google Translate, and its name, or nil if it is not initialized
+func (gt *Client) Comparison() (Translator, string) {
+	switch {
+	case gt.useLLM() && gt.hasNMT():
+		return backendTranslator{gt, "nmt"}, "nmt"
+	case !gt.useLLM() && gt.llmClient != nil:
+		return backendTranslator{gt, "gemini"}, "gemini"
+	}
+	return nil, ""
+}
@@ -0,0 +1,42 @@
+package translate
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// translateAuth returns the options authenticating the Google Translate clients over tr.
+// The files given explicitly come first: a service account from Credentials, then the API key
+// from KeyFile; then a service account named by GOOGLE_APPLICATION_CREDENTIALS, then the API
+// key from GOOGLE_TRANSLATE_APIKEY.
+func translateAuth(ctx context.Context, opts Options, tr http.RoundTripper) ([]option.ClientOption, error) {
+	file := opts.Credentials
+	if file != "" {
+		slog.Info("authenticating with the service account from -credentials", "file", file)
+	} else if opts.KeyFile == "" {
+		if file = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); file != "" {
+			slog.Info("authenticating with the service account from GOOGLE_APPLICATION_CREDENTIALS", "file", file)
+		}
+	}
+	if file != "" {
+		// the credentials wrap tr so that requests still go through the proxy
+		rt, err := htransport.NewTransport(ctx, tr, option.WithCredentialsFile(file), option.WithScopes(cloudPlatformScope))
+		if err != nil {
+			return nil, err
+		}
+		return []option.ClientOption{option.WithHTTPClient(&http.Client{Transport: rt})}, nil
+	}
+	key, err := readAPIKey("GOOGLE_TRANSLATE_APIKEY", opts.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("authenticating with an API key")
+	return googleOptions(key, tr), nil
+}
@@ -0,0 +1,35 @@
+package translate
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTranslateAuthPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte("key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// a missing service account file only fails when it is the one used
+	missing := filepath.Join(dir, "missing.json")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", missing)
+	t.Setenv("GOOGLE_TRANSLATE_APIKEY", "")
+	tests := []struct {
+		opts    Options
+		wantErr bool
+	}{
+		{Options{KeyFile: keyFile}, false},
+		{Options{}, true},
+		{Options{Credentials: missing, KeyFile: keyFile}, true},
+	}
+	for _, tt := range tests {
+		_, err := translateAuth(context.Background(), tt.opts, http.DefaultTransport)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("translateAuth(%+v) = %v, want an error %t", tt.opts, err, tt.wantErr)
+		}
+	}
+}
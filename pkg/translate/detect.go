@@ -0,0 +1,36 @@
+package translate
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+
+	cloudtranslate "cloud.google.com/go/translate"
+)
+
+// detectionMargin is the difference of confidence below which two detected languages are a tie
+const detectionMargin = 0.1
+
+// pickDetection returns the most confident of the detected languages; when others are within
+// detectionMargin of it, the first of them that is known or learned is picked instead
+func (gt *Client) pickDetection(dets []cloudtranslate.Detection) cloudtranslate.Detection {
+	dets = slices.Clone(dets)
+	slices.SortStableFunc(dets, func(a, b cloudtranslate.Detection) int {
+		return cmp.Compare(b.Confidence, a.Confidence)
+	})
+	for i, det := range dets {
+		slog.Debug("detection candidate", "rank", i+1, "lang", det.Language, "confidence", det.Confidence)
+	}
+	for _, det := range dets {
+		if dets[0].Confidence-det.Confidence >= detectionMargin {
+			break
+		}
+		if slices.ContainsFunc(gt.languages, func(lang string) bool { return SameLanguage(det.Language.String(), lang) }) {
+			if det.Language != dets[0].Language {
+				slog.Info("preferring a close candidate in the known or learned languages", "lang", det.Language, "over", dets[0].Language)
+			}
+			return det
+		}
+	}
+	return dets[0]
+}
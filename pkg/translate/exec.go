@@ -0,0 +1,66 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// ErrNoExecCmd is returned for the exec backend without a command
+var ErrNoExecCmd = errors.New("the exec backend needs -exec-cmd")
+
+// execArgs splits the command template on spaces and fills in the placeholders of every
+// argument: {{source}} and {{target}} with the language codes and {{text}} with the text
+func execArgs(template, source, target, text string) []string {
+	r := strings.NewReplacer("{{source}}", source, "{{target}}", target, "{{text}}", text)
+	var args []string
+	for _, field := range strings.Fields(template) {
+		args = append(args, r.Replace(field))
+	}
+	return args
+}
+
+// requestExec runs the command template of ExecCmd to translate text into lang, passing text on stdin
+// and reading the translation from stdout; the source language is the one of Source, or the
+// one guessed from the script of text
+func (gt *Client) requestExec(ctx context.Context, lang language.Tag, text string) (string, error) {
+	source := gt.source
+	if source == "" {
+		if source, _, _ = ScriptLanguage(text); source == "" {
+			source = "auto"
+		}
+	}
+	args := execArgs(gt.execCmd, source, lang.String(), text)
+	slog.Debug("running the exec backend", "args", args)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", args[0], err)
+	}
+	gt.recordUsage("exec", lang.String(), len([]rune(text)), 0)
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// detectScript returns the language of text guessed from its script, for the backends
+// unable to detect it, halving the confidence when the script is shared by several languages
+func detectScript(text string) (string, float64, error) {
+	lang, share, reliable := ScriptLanguage(text)
+	if lang == "" {
+		return "", 0, ErrNoLetters
+	}
+	if !reliable {
+		share /= 2
+	}
+	return lang, share, nil
+}
@@ -0,0 +1,31 @@
+package translate
+
+import (
+	"log/slog"
+
+	"golang.org/x/text/language"
+)
+
+// UsedFallback reports whether any request so far was served by the fallback
+func (gt *Client) UsedFallback() bool {
+	return gt.fellBack.Load()
+}
+
+// translateFallback translates text into lang with Google Translate after the LLM failed with cause
+func (gt *Client) translateFallback(lang language.Tag, text string, cause error) (string, error) {
+	slog.Warn("the LLM translation failed, falling back to nmt", "err", cause)
+	// the fallback gets a fresh timeout, since the LLM may have used it all
+	ctx, cancel := gt.requestContext()
+	defer cancel()
+	trans, err := withRetry(ctx, gt.retries, func() (string, error) {
+		return gt.requestNMT(ctx, lang, text)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+	gt.fellBack.Store(true)
+	return trans, nil
+}
@@ -0,0 +1,95 @@
+package translate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// glossaryEntry forces source to be translated as target
+type glossaryEntry struct {
+	source string
+	target string
+}
+
+// Glossary is a list of forced term translations, the longest terms first
+type Glossary []glossaryEntry
+
+// LoadGlossary reads a file of source=target lines, ignoring blank lines and # comments
+func LoadGlossary(path string) (Glossary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var g Glossary
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		source, target, ok := strings.Cut(line, "=")
+		source, target = strings.TrimSpace(source), strings.TrimSpace(target)
+		if !ok || source == "" {
+			return nil, fmt.Errorf("%s:%d: expected source=target", path, n)
+		}
+		g = append(g, glossaryEntry{source: source, target: target})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(g, func(i, j int) bool { return len(g[i].source) > len(g[j].source) })
+	return g, nil
+}
+
+// instruction returns the system instruction asking the LLM backends to follow the glossary
+func (g Glossary) instruction() string {
+	if len(g) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\nAlways translate the following terms exactly as indicated:")
+	for _, e := range g {
+		fmt.Fprintf(&sb, "\n- %s: %s", e.source, e.target)
+	}
+	return sb.String()
+}
+
+// apply replaces every whole-word occurrence of the glossary terms in text
+func (g Glossary) apply(text string) string {
+	for _, e := range g {
+		text = replaceWord(text, e.source, e.target)
+	}
+	return text
+}
+
+// replaceWord replaces the occurrences of term in s that are not part of a longer word
+func replaceWord(s, term, repl string) string {
+	var sb strings.Builder
+	for {
+		i := strings.Index(s, term)
+		if i < 0 {
+			sb.WriteString(s)
+			return sb.String()
+		}
+		before, _ := utf8.DecodeLastRuneInString(s[:i])
+		after, _ := utf8.DecodeRuneInString(s[i+len(term):])
+		sb.WriteString(s[:i])
+		if isWordRune(before) || isWordRune(after) {
+			sb.WriteString(term)
+		} else {
+			sb.WriteString(repl)
+		}
+		s = s[i+len(term):]
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
@@ -0,0 +1,79 @@
+package translate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeGlossary(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "glossary.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGlossary(t *testing.T) {
+	path := writeGlossary(t, "# terms\nlearning = apprentissage\n\nmachine learning=apprentissage automatique\nC++ = C++\n")
+	g, err := LoadGlossary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Glossary{{"machine learning", "apprentissage automatique"}, {"learning", "apprentissage"}, {"C++", "C++"}}
+	if len(g) != len(want) {
+		t.Fatalf("LoadGlossary = %v, want %v", g, want)
+	}
+	for i := range want {
+		if g[i] != want[i] {
+			t.Errorf("entry %d = %v, want %v", i, g[i], want[i])
+		}
+	}
+
+	tests := []struct {
+		text, want string
+	}{
+		{"machine learning, learning; relearning (learning).", "apprentissage automatique, apprentissage; relearning (apprentissage)."},
+		{"learning_rate and learning2 stay", "learning_rate and learning2 stay"},
+		{"«learning» and \"learning\"!", "«apprentissage» and \"apprentissage\"!"},
+		{"Learning is case sensitive", "Learning is case sensitive"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := g.apply(tt.text); got != tt.want {
+			t.Errorf("apply(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	opts := Options{Known: "en", Learn: []string{"fr"}, Glossary: g}
+	if got := opts.prompt(); !strings.HasSuffix(got, "\n- machine learning: apprentissage automatique\n- learning: apprentissage\n- C++: C++") {
+		t.Errorf("prompt = %q, want it to end with the glossary", got)
+	}
+	if settingsOf(opts) == settingsOf(Options{Known: "en", Learn: []string{"fr"}}) {
+		t.Error("the translations with and without the glossary share their cache key")
+	}
+}
+
+func TestLoadGlossaryInvalid(t *testing.T) {
+	path := writeGlossary(t, "a=b\nno separator\n")
+	if _, err := LoadGlossary(path); err == nil || !strings.Contains(err.Error(), path+":2:") {
+		t.Errorf("LoadGlossary = %v, want an error at line 2", err)
+	}
+}
+
+func TestReplaceWord(t *testing.T) {
+	tests := []struct {
+		s, term, repl, want string
+	}{
+		{"cat, catalog, cat.", "cat", "chat", "chat, catalog, chat."},
+		{"bobcat cat_s cat", "cat", "chat", "bobcat cat_s chat"},
+		{"(猫) 猫", "猫", "cat", "(cat) cat"},
+	}
+	for _, tt := range tests {
+		if got := replaceWord(tt.s, tt.term, tt.repl); got != tt.want {
+			t.Errorf("replaceWord(%q, %q, %q) = %q, want %q", tt.s, tt.term, tt.repl, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package translate
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// splitKeys returns the API keys listed in key, separated by commas or newlines,
+// skipping blank lines and the ones starting with #
+func splitKeys(key string) []string {
+	var keys []string
+	for _, line := range strings.Split(key, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, k := range strings.Split(line, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys
+}
+
+// keyRing rotates the requests between the Gemini models created with each API key,
+// remembering the next one in a state file so that the rotation goes on across runs
+type keyRing struct {
+	mu      sync.Mutex
+	models  []*genai.GenerativeModel
+	clients []*genai.Client
+	next    int
+	// state is the file holding next, not saved when empty
+	state string
+}
+
+// newKeyRing returns an empty ring starting where the last run left off, or at the first key
+// if the state file is missing or invalid
+func newKeyRing(state string) *keyRing {
+	r := &keyRing{state: state}
+	if data, err := os.ReadFile(state); err == nil {
+		if next, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && next >= 0 {
+			r.next = next
+		}
+	}
+	return r
+}
+
+func (r *keyRing) add(client *genai.Client, model *genai.GenerativeModel) {
+	r.clients = append(r.clients, client)
+	r.models = append(r.models, model)
+}
+
+func (r *keyRing) len() int { return len(r.models) }
+
+// take returns the model of the next key and moves the rotation forward
+func (r *keyRing) take() *genai.GenerativeModel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.next % len(r.models)
+	r.next = (i + 1) % len(r.models)
+	if len(r.models) > 1 && r.state != "" {
+		err := os.MkdirAll(filepath.Dir(r.state), 0o755)
+		if err == nil {
+			err = os.WriteFile(r.state, []byte(strconv.Itoa(r.next)), 0o644)
+		}
+		if err != nil {
+			slog.Warn("unable to save the next gemini key", "err", err)
+		}
+	}
+	slog.Debug("using gemini key", "index", i)
+	return r.models[i]
+}
+
+func (r *keyRing) close() {
+	for _, c := range r.clients {
+		c.Close()
+	}
+}
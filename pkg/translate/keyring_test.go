@@ -0,0 +1,47 @@
+package translate
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func TestKeyRingState(t *testing.T) {
+	models := []*genai.GenerativeModel{{}, {}, {}}
+	tests := []struct {
+		state string
+		want  int
+	}{
+		{"1\n", 1},
+		{"5", 2},
+		{"-3", 0},
+		{"garbage", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		state := filepath.Join(t.TempDir(), "next")
+		if err := os.WriteFile(state, []byte(tt.state), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		r := newKeyRing(state)
+		for _, m := range models {
+			r.add(nil, m)
+		}
+		for i := range len(models) + 1 {
+			want := models[(tt.want+i)%len(models)]
+			if got := r.take(); got != want {
+				t.Errorf("state %q: take %d returned the wrong key", tt.state, i)
+			}
+		}
+		data, err := os.ReadFile(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (tt.want + len(models) + 1) % len(models); string(data) != strconv.Itoa(want) {
+			t.Errorf("state %q: saved %q, want %d", tt.state, data, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package translate
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// llmLanguages are the languages listed for the LLM backends, which don't report the ones they support
+var llmLanguages = []string{
+	"af", "ar", "bg", "bn", "ca", "cs", "da", "de", "el", "en", "es", "et", "fa", "fi", "fr",
+	"he", "hi", "hr", "hu", "id", "it", "ja", "ko", "lt", "lv", "ms", "nl", "no", "pl", "pt",
+	"ro", "ru", "sk", "sl", "sr", "sv", "sw", "ta", "th", "tl", "tr", "uk", "ur", "vi", "zh",
+}
+
+// staticLanguages returns llmLanguages with their names in the display language
+func staticLanguages(lang language.Tag) []Language {
+	namer := display.Tags(lang)
+	langs := make([]Language, 0, len(llmLanguages))
+	for _, code := range llmLanguages {
+		tag := language.MustParse(code)
+		langs = append(langs, Language{Name: namer.Name(tag), Tag: tag})
+	}
+	return langs
+}
+
+// langsTTL is how long the supported languages are cached
+const langsTTL = 7 * 24 * time.Hour
+
+// langsPath returns the path of the cached languages named in lang
+func (gt *Client) langsPath(lang language.Tag) string {
+	return filepath.Join(gt.langsDir, "langs-"+lang.String()+".json")
+}
+
+// cachedLanguages returns the languages named in lang cached less than langsTTL ago, if any
+func (gt *Client) cachedLanguages(lang language.Tag) ([]Language, bool) {
+	if gt.langsDir == "" || gt.refreshLangs {
+		return nil, false
+	}
+	path := gt.langsPath(lang)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > langsTTL {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var langs []Language
+	if err := json.Unmarshal(data, &langs); err != nil {
+		slog.Warn("unable to read the cached languages", "path", path, "err", err)
+		return nil, false
+	}
+	return langs, true
+}
+
+// cacheLanguages saves the languages named in lang for cachedLanguages
+func (gt *Client) cacheLanguages(lang language.Tag, langs []Language) {
+	if gt.langsDir == "" {
+		return
+	}
+	data, err := json.Marshal(langs)
+	if err == nil {
+		err = os.MkdirAll(gt.langsDir, 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(gt.langsPath(lang), data, 0o644)
+	}
+	if err != nil {
+		slog.Warn("unable to cache the languages", "err", err)
+	}
+}
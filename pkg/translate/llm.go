@@ -0,0 +1,136 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"golang.org/x/text/language"
+	"google.golang.org/api/iterator"
+)
+
+const detectInstruction = "You are a language detector.\n" +
+	"Whenever you receive a message, you will only respond with the ISO 639-1 code of the language it is written in.\n" +
+	"Remember: the output should only contain the language code, such as en or ko."
+
+// generate sends text to the LLM backend in use with the given system instruction,
+// target being the language of the expected output, if any, as recorded in the usage log
+func (gt *Client) generate(ctx context.Context, target, system, text string) (string, error) {
+	if gt.llmClient != nil {
+		// a rate limited key moves on to the next one right away
+		for tries := 1; ; tries++ {
+			resp, err := gt.generateGemini(ctx, target, system, text)
+			if StatusCode(err) != http.StatusTooManyRequests || tries >= gt.llms.len() {
+				return resp, err
+			}
+			slog.Warn("gemini key rate limited, trying the next one", "err", err)
+		}
+	}
+	generate := gt.ollamaGenerate
+	if gt.openaiURL != "" {
+		generate = gt.openaiGenerate
+	} else if gt.anthropicKey != "" {
+		generate = gt.anthropicGenerate
+	}
+	resp, tokens, err := generate(ctx, system, text)
+	if err != nil {
+		return "", err
+	}
+	gt.recordUsage(gt.backend, target, len([]rune(text)), tokens)
+	return resp, nil
+}
+
+// generateGemini sends text to Gemini with the next API key, along with the system instruction
+func (gt *Client) generateGemini(ctx context.Context, target, system, text string) (string, error) {
+	llm := *gt.llms.take()
+	llm.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(system)}}
+	if gt.stream {
+		return gt.generateStream(ctx, &llm, target, text)
+	}
+	resp, err := llm.GenerateContent(ctx, genai.Text(text))
+	if err != nil {
+		return "", err
+	}
+	var tokens int32
+	if resp.UsageMetadata != nil {
+		tokens = resp.UsageMetadata.TotalTokenCount
+	}
+	gt.recordUsage("gemini", target, len([]rune(text)), tokens)
+	return geminiText(resp)
+}
+
+// geminiText returns the first part of the first candidate of resp, which has none when the
+// answer was blocked
+func geminiText(resp *genai.GenerateContentResponse) (string, error) {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return "", errors.New("gemini: empty response")
+	}
+	return fmt.Sprintf("%s", resp.Candidates[0].Content.Parts[0]), nil
+}
+
+// generateStream is like generate for Gemini, but logs the response as it arrives;
+// if the stream breaks after some output, what was received so far is returned
+func (gt *Client) generateStream(ctx context.Context, llm *genai.GenerativeModel, target, text string) (string, error) {
+	iter := llm.GenerateContentStream(ctx, genai.Text(text))
+	var sb strings.Builder
+	var tokens int32
+	for {
+		resp, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		} else if err != nil {
+			if sb.Len() == 0 {
+				return "", err
+			}
+			slog.Warn("stream interrupted, keeping the partial response", "err", err)
+			break
+		}
+		if resp.UsageMetadata != nil {
+			tokens = resp.UsageMetadata.TotalTokenCount
+		}
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+			continue
+		}
+		for _, part := range resp.Candidates[0].Content.Parts {
+			chunk := fmt.Sprintf("%s", part)
+			slog.Debug("received", "chunk", chunk)
+			sb.WriteString(chunk)
+		}
+	}
+	gt.recordUsage("gemini", target, len([]rune(text)), tokens)
+	if sb.Len() == 0 {
+		return "", errors.New("gemini: empty response")
+	}
+	return sb.String(), nil
+}
+
+// targetPrompt returns the system instruction asking for a translation into lang,
+// so that the LLM backends follow the same direction as the detection-based one
+func (gt *Client) targetPrompt(lang language.Tag) string {
+	return gt.prompt + fmt.Sprintf("\nThis message must be translated into %s.", LanguageName(lang.String()))
+}
+
+// llmDetect asks the LLM backend for the language of text
+func (gt *Client) llmDetect(text string) (string, error) {
+	ctx, cancel := gt.requestContext()
+	defer cancel()
+	resp, err := withRetry(ctx, gt.retries, func() (string, error) {
+		return gt.generate(ctx, "", detectInstruction, text)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+	tag, err := language.Parse(strings.Trim(strings.TrimSpace(resp), "`'\"."))
+	if err != nil {
+		return "", fmt.Errorf("unexpected language code %q: %w", resp, err)
+	}
+	base, _ := tag.Base()
+	return base.String(), nil
+}
@@ -0,0 +1,25 @@
+package translate
+
+import (
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func TestGeminiText(t *testing.T) {
+	tests := []struct {
+		resp *genai.GenerateContentResponse
+		want string
+	}{
+		{&genai.GenerateContentResponse{}, ""},
+		{&genai.GenerateContentResponse{Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonSafety}}}, ""},
+		{&genai.GenerateContentResponse{Candidates: []*genai.Candidate{{Content: &genai.Content{}}}}, ""},
+		{&genai.GenerateContentResponse{Candidates: []*genai.Candidate{{Content: &genai.Content{Parts: []genai.Part{genai.Text("hello")}}}}}, "hello"},
+	}
+	for i, tt := range tests {
+		got, err := geminiText(tt.resp)
+		if got != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("%d: geminiText = %q, %v, want %q", i, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const defaultOllamaHost = "http://localhost:11434"
+
+type ollamaRequest struct {
+	Model   string        `json:"model"`
+	System  string        `json:"system"`
+	Prompt  string        `json:"prompt"`
+	Stream  bool          `json:"stream"`
+	Options ollamaOptions `json:"options"`
+}
+
+type ollamaOptions struct {
+	Temperature float32 `json:"temperature"`
+	TopP        float32 `json:"top_p,omitempty"`
+}
+
+type ollamaResponse struct {
+	Response        string `json:"response"`
+	Error           string `json:"error"`
+	PromptEvalCount int32  `json:"prompt_eval_count"`
+	EvalCount       int32  `json:"eval_count"`
+}
+
+// OllamaHost returns the base URL of the Ollama server, honoring OLLAMA_HOST
+func OllamaHost() string {
+	host := os.Getenv("OLLAMA_HOST")
+	if host == "" {
+		return defaultOllamaHost
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return strings.TrimRight(host, "/")
+}
+
+// ollamaGenerate returns the response of the model and the number of tokens evaluated
+func (gt *Client) ollamaGenerate(ctx context.Context, system, text string) (string, int32, error) {
+	body, err := json.Marshal(ollamaRequest{
+		Model:  gt.model,
+		System: system,
+		Prompt: text,
+		Options: ollamaOptions{
+			Temperature: gt.temperature,
+			TopP:        gt.topP,
+		},
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gt.ollamaURL+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := gt.httpClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	var out ollamaResponse
+	if resp.StatusCode != http.StatusOK {
+		// the body of an error is not always JSON, such as the pages of a proxy
+		json.NewDecoder(resp.Body).Decode(&out)
+		return "", 0, newStatusError(resp, out.Error)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", 0, err
+	}
+	return out.Response, out.PromptEvalCount + out.EvalCount, nil
+}
@@ -0,0 +1,82 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+const DefaultOpenAIURL = "https://api.openai.com/v1"
+
+type openaiMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openaiRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openaiMessage `json:"messages"`
+	Temperature float32         `json:"temperature"`
+	TopP        float32         `json:"top_p,omitempty"`
+}
+
+type openaiResponse struct {
+	Choices []struct {
+		Message openaiMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		TotalTokens int32 `json:"total_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// openaiGenerate returns the response of the chat completions endpoint and the number of tokens used
+func (gt *Client) openaiGenerate(ctx context.Context, system, text string) (string, int32, error) {
+	body, err := json.Marshal(openaiRequest{
+		Model: gt.model,
+		Messages: []openaiMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: text},
+		},
+		Temperature: gt.temperature,
+		TopP:        gt.topP,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gt.openaiURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if gt.openaiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+gt.openaiKey)
+	}
+	resp, err := gt.httpClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	var out openaiResponse
+	if resp.StatusCode != http.StatusOK {
+		// the body of an error is not always JSON, such as the pages of a proxy
+		json.NewDecoder(resp.Body).Decode(&out)
+		msg := ""
+		if out.Error != nil {
+			msg = out.Error.Message
+		}
+		return "", 0, newStatusError(resp, msg)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", 0, err
+	}
+	if len(out.Choices) == 0 {
+		return "", 0, errors.New("openai: empty response")
+	}
+	return out.Choices[0].Message.Content, out.Usage.TotalTokens, nil
+}
@@ -0,0 +1,32 @@
+package translate
+
+import (
+	"errors"
+)
+
+const paraphraseInstruction = "You are an editor.\n" +
+	"Whenever you receive a message, you will only respond with the message rewritten more clearly, in the same language.\n" +
+	"Keep its meaning and tone, and do not translate it.\n" +
+	"Remember: the output should only contain the rewritten message."
+
+// ErrNoParaphrase is returned when paraphrasing with a backend other than an LLM
+var ErrNoParaphrase = errors.New("-paraphrase requires an LLM backend")
+
+// Paraphrase rewrites text more clearly in its own language
+func (gt *Client) Paraphrase(text string) (string, error) {
+	if !gt.useLLM() {
+		return "", ErrNoParaphrase
+	}
+	ctx, cancel := gt.requestContext()
+	defer cancel()
+	resp, err := withRetry(ctx, gt.retries, func() (string, error) {
+		return gt.generate(ctx, "", paraphraseInstruction, text)
+	})
+	if err != nil {
+		return "", err
+	}
+	if gt.trim {
+		resp = trimResponse(resp, text)
+	}
+	return resp, nil
+}
@@ -0,0 +1,90 @@
+package translate
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+const systemInstruction = "You are a language translator.\n" +
+	"Whenever you receive a message, you will only respond with a translated version of the message.\n" +
+	"The rules are as follows: if the message is in %[1]s, translate it into %[2]s, otherwise, translate it into %[1]s.\n" +
+	"You should strive for accuracy on the meaning and not on a literal translation.\n" +
+	"Remember: the output should only contain the translated message."
+
+const domainInstruction = "\nThe message comes from %s: use the tone and terminology that fit it."
+
+// the speech levels accepted as the Register
+const (
+	RegisterFormal   = "formal"
+	RegisterInformal = "informal"
+)
+
+var registerInstructions = map[string]string{
+	RegisterFormal:   "\nUse a formal, polite register, such as the polite speech levels of Korean or Japanese.",
+	RegisterInformal: "\nUse an informal, casual register, as between friends, such as the casual speech levels of Korean or Japanese.",
+}
+
+// CheckRegister validates a speech level given as the register
+func CheckRegister(register string) error {
+	if _, ok := registerInstructions[register]; !ok {
+		return fmt.Errorf("unknown register %q, expected formal or informal", register)
+	}
+	return nil
+}
+
+const markdownInstruction = "\nThe message is formatted as markdown: preserve its structure, such as headings, lists, links and emphasis, exactly as it is."
+
+// LanguageName returns the English name of the language code, or the code itself if unknown
+func LanguageName(code string) string {
+	tag, err := language.Parse(code)
+	if err != nil {
+		return code
+	}
+	if name := display.English.Tags().Name(tag); name != "" {
+		return name
+	}
+	return code
+}
+
+// LanguageLabel returns the English name of the language code followed by the code, such as
+// "Korean (ko)", or the code alone if unknown
+func LanguageLabel(code string) string {
+	if name := LanguageName(code); name != code {
+		return name + " (" + code + ")"
+	}
+	return code
+}
+
+// SameLanguage reports whether two language codes name the same language, a code without a
+// region matching any region of its language, such as zh and zh-TW but not zh-CN and zh-TW
+func SameLanguage(a, b string) bool {
+	if strings.EqualFold(a, b) {
+		return true
+	}
+	return BaseLanguage(a) == BaseLanguage(b) && (!strings.Contains(a, "-") || !strings.Contains(b, "-"))
+}
+
+// prompt returns the system instruction given to the LLM backends
+func (opts Options) prompt() string {
+	prompt := opts.CustomPrompt
+	if prompt == "" {
+		known := LanguageName(opts.Known)
+		var learn []string
+		for _, code := range opts.Learn {
+			learn = append(learn, LanguageName(code))
+		}
+		prompt = fmt.Sprintf(systemInstruction, known, strings.Join(learn, " and "))
+	}
+	if opts.Domain != "" {
+		prompt += fmt.Sprintf(domainInstruction, opts.Domain)
+	}
+	prompt += registerInstructions[opts.Register]
+	if opts.Format == "markdown" {
+		prompt += markdownInstruction
+	}
+	prompt += opts.Glossary.instruction()
+	return prompt
+}
@@ -0,0 +1,45 @@
+package translate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPromptRegister(t *testing.T) {
+	base := Options{Known: "en", Learn: []string{"ko"}}
+	if got := base.prompt(); strings.Contains(got, "register") {
+		t.Errorf("prompt without a register mentions one: %q", got)
+	}
+	for _, register := range []string{RegisterFormal, RegisterInformal} {
+		opts := base
+		opts.Register = register
+		got := opts.prompt()
+		if !strings.HasPrefix(got, base.prompt()) || !strings.HasSuffix(got, registerInstructions[register]) {
+			t.Errorf("%s prompt = %q, want the default one followed by %q", register, got, registerInstructions[register])
+		}
+	}
+	formal, informal := base, base
+	formal.Register, informal.Register = RegisterFormal, RegisterInformal
+	if settingsOf(formal) == settingsOf(informal) {
+		t.Error("the formal and informal translations share their cache key")
+	}
+}
+
+func TestPromptDomain(t *testing.T) {
+	opts := Options{Known: "en", Learn: []string{"ko"}, Register: RegisterFormal}
+	plain := opts.prompt()
+	opts.Domain = "software documentation"
+	got := opts.prompt()
+	want := "\nThe message comes from software documentation: use the tone and terminology that fit it."
+	if !strings.Contains(got, want) || strings.Contains(plain, "comes from") {
+		t.Errorf("prompt = %q, want it to contain %q", got, want)
+	}
+	if !strings.HasSuffix(got, registerInstructions[RegisterFormal]) {
+		t.Errorf("prompt = %q, want the register kept after the domain", got)
+	}
+	other := opts
+	other.Domain = "casual chat"
+	if settingsOf(opts) == settingsOf(other) {
+		t.Error("the translations of different domains share their cache key")
+	}
+}
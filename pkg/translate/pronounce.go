@@ -0,0 +1,23 @@
+package translate
+
+import (
+	"fmt"
+	"strings"
+)
+
+const pronounceInstruction = "You are a phonetician.\n" +
+	"Whenever you receive a word written in %s, you will only respond with its pronunciation in the International Phonetic Alphabet, between slashes.\n" +
+	"Remember: the output should only contain the transcription, such as /həˈloʊ/."
+
+// Pronounce returns the IPA transcription of word, which is written in lang
+func (gt *Client) Pronounce(lang, word string) (string, error) {
+	if !gt.useLLM() {
+		return "", fmt.Errorf("the pronunciation of %s requires an LLM backend", LanguageName(lang))
+	}
+	ctx, cancel := gt.requestContext()
+	defer cancel()
+	resp, err := withRetry(ctx, gt.retries, func() (string, error) {
+		return gt.generate(ctx, lang, fmt.Sprintf(pronounceInstruction, LanguageName(lang)), word)
+	})
+	return strings.TrimSpace(resp), err
+}
@@ -0,0 +1,44 @@
+package translate
+
+import (
+	"net/http"
+	"net/url"
+
+	"google.golang.org/api/option"
+)
+
+// httpTransport returns a transport going through proxy, or through the proxy configured by
+// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables when empty
+func httpTransport(proxy string) (*http.Transport, error) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = http.ProxyFromEnvironment
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return nil, err
+		}
+		tr.Proxy = http.ProxyURL(u)
+	}
+	return tr, nil
+}
+
+// apiKeyTransport authenticates the requests to Google APIs with an API key
+type apiKeyTransport struct {
+	key  string
+	base http.RoundTripper
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Goog-Api-Key", t.key)
+	return t.base.RoundTrip(req)
+}
+
+// googleOptions returns the options for the Google clients to authenticate with key over tr.
+// Since a custom HTTP client disables the API key option, the key is also set by the transport.
+func googleOptions(key string, tr http.RoundTripper) []option.ClientOption {
+	return []option.ClientOption{
+		option.WithAPIKey(key),
+		option.WithHTTPClient(&http.Client{Transport: &apiKeyTransport{key: key, base: tr}}),
+	}
+}
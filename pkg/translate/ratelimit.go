@@ -0,0 +1,119 @@
+package translate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/api/googleapi"
+)
+
+// maxRetryAfter is the longest wait for a rate limit to clear before giving up on the request
+const maxRetryAfter = 30 * time.Second
+
+// the reasons reported by the Google APIs when a request is throttled or the quota is spent
+var (
+	rateLimitReasons = []string{"rateLimitExceeded", "userRateLimitExceeded", "RATE_LIMIT_EXCEEDED"}
+	quotaReasons     = []string{"dailyLimitExceeded", "quotaExceeded", "billingNotEnabled"}
+)
+
+// LimitError is returned when a request keeps failing because of a rate limit or a spent quota
+type LimitError struct {
+	// quota is set when the quota is spent, so waiting a little won't help
+	quota bool
+	// retryAfter is the wait suggested by the server, zero if unknown
+	retryAfter time.Duration
+	err        error
+}
+
+func (e *LimitError) Error() string {
+	return e.Message() + ": " + e.err.Error()
+}
+
+func (e *LimitError) Unwrap() error { return e.err }
+
+// Message describes the limit that was hit and what can be done about it
+func (e *LimitError) Message() string {
+	switch {
+	case e.quota:
+		return "quota exhausted, check the quota and billing of the API key"
+	case e.retryAfter > 0:
+		return fmt.Sprintf("rate limited, try again in %.0f seconds", math.Ceil(e.retryAfter.Seconds()))
+	}
+	return "rate limited, try again later"
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, in seconds or as an HTTP date
+func parseRetryAfter(header string) time.Duration {
+	if secs, err := strconv.Atoi(header); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// errorReasons returns the reasons given by the Google APIs for err
+func errorReasons(err error) []string {
+	var reasons []string
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) && apiErr.Reason() != "" {
+		reasons = append(reasons, apiErr.Reason())
+	}
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) {
+		for _, item := range gErr.Errors {
+			reasons = append(reasons, item.Reason)
+		}
+	}
+	return reasons
+}
+
+// LimitOf returns the rate limit or quota behind err, if that is why it failed
+func LimitOf(err error) (*LimitError, bool) {
+	var lErr *LimitError
+	if errors.As(err, &lErr) {
+		return lErr, true
+	}
+	reasons := errorReasons(err)
+	hasReason := func(known []string) bool {
+		return slices.ContainsFunc(reasons, func(r string) bool { return slices.Contains(known, r) })
+	}
+	code := StatusCode(err)
+	if code != http.StatusTooManyRequests && !hasReason(rateLimitReasons) && !hasReason(quotaReasons) {
+		return nil, false
+	}
+
+	lErr = &LimitError{quota: hasReason(quotaReasons), err: err}
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		if info := apiErr.Details().RetryInfo; info != nil {
+			lErr.retryAfter = info.GetRetryDelay().AsDuration()
+		}
+		if failure := apiErr.Details().QuotaFailure; failure != nil {
+			// a daily quota won't be back in the next few seconds
+			for _, v := range failure.GetViolations() {
+				desc := strings.ToLower(v.GetSubject() + " " + v.GetDescription())
+				lErr.quota = lErr.quota || strings.Contains(desc, "perday") || strings.Contains(desc, "per day")
+			}
+		}
+	}
+	var gErr *googleapi.Error
+	if lErr.retryAfter == 0 && errors.As(err, &gErr) && gErr.Header != nil {
+		lErr.retryAfter = parseRetryAfter(gErr.Header.Get("Retry-After"))
+	}
+	var sErr *statusError
+	if errors.As(err, &sErr) {
+		lErr.retryAfter = max(lErr.retryAfter, sErr.retryAfter)
+		// OpenAI answers with a 429 too once the credit is spent
+		lErr.quota = lErr.quota || strings.Contains(sErr.msg, "quota")
+	}
+	return lErr, true
+}
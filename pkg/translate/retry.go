@@ -0,0 +1,103 @@
+package translate
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/api/googleapi"
+)
+
+const retryBaseDelay = 200 * time.Millisecond
+
+// statusError is returned by the backends that talk HTTP directly when the server answers with an error
+type statusError struct {
+	code int
+	msg  string
+	// retryAfter is the wait asked by the Retry-After header, zero without one
+	retryAfter time.Duration
+}
+
+// newStatusError returns the statusError of resp, whose body gave msg
+func newStatusError(resp *http.Response, msg string) *statusError {
+	return &statusError{code: resp.StatusCode, msg: msg, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+}
+
+func (e *statusError) Error() string {
+	if e.msg == "" {
+		return http.StatusText(e.code)
+	}
+	return http.StatusText(e.code) + ": " + e.msg
+}
+
+// StatusCode returns the HTTP status code carried by err, or 0 if it has none
+func StatusCode(err error) int {
+	var apiErr *apierror.APIError
+	var gErr *googleapi.Error
+	var sErr *statusError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.HTTPCode()
+	case errors.As(err, &gErr):
+		return gErr.Code
+	case errors.As(err, &sErr):
+		return sErr.code
+	}
+	return 0
+}
+
+// isTransient reports whether err is worth retrying: rate limits, server errors and network timeouts
+func isTransient(err error) bool {
+	if limit, ok := LimitOf(err); ok {
+		return !limit.quota
+	}
+	code := StatusCode(err)
+	var netErr net.Error
+	if code == 0 && errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+	switch code {
+	case http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// withRetry calls fn up to attempts times, with exponential backoff, while it fails with a transient error;
+// rate limits wait as long as the server asks, and are reported as a LimitError when they persist
+func withRetry[T any](ctx context.Context, attempts int, fn func() (T, error)) (T, error) {
+	delay := retryBaseDelay
+	for i := 1; ; i++ {
+		res, err := fn()
+		if err == nil {
+			return res, nil
+		}
+		limit, limited := LimitOf(err)
+		if limited {
+			err = limit
+		}
+		if i >= attempts || !isTransient(err) {
+			return res, err
+		}
+		wait := delay
+		if limited && limit.retryAfter > 0 {
+			deadline, ok := ctx.Deadline()
+			if limit.retryAfter > maxRetryAfter || ok && time.Until(deadline) < limit.retryAfter {
+				return res, err
+			}
+			wait = limit.retryAfter
+		}
+		slog.Warn("request failed, retrying", "attempt", i, "attempts", attempts, "delay", wait, "err", err)
+		select {
+		case <-ctx.Done():
+			return res, ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
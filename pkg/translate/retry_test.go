@@ -0,0 +1,49 @@
+package translate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// redirect sends every request to the server at target
+type redirect struct{ target *url.URL }
+
+func (r redirect) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme, req.URL.Host = r.target.Scheme, r.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestStatusErrorWithoutJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("<html><body>503 Service Unavailable</body></html>"))
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+	gt := &Client{
+		httpClient: &http.Client{Transport: redirect{target}},
+		ollamaURL:  srv.URL,
+		openaiURL:  srv.URL,
+	}
+	backends := map[string]func(context.Context, string, string) (string, int32, error){
+		"ollama":    gt.ollamaGenerate,
+		"openai":    gt.openaiGenerate,
+		"anthropic": gt.anthropicGenerate,
+	}
+	for name, generate := range backends {
+		_, _, err := generate(context.Background(), "system", "text")
+		if code := StatusCode(err); code != http.StatusServiceUnavailable {
+			t.Errorf("%s: got %v with status %d, want status 503", name, err, code)
+		}
+		if !isTransient(err) {
+			t.Errorf("%s: %v is not retried", name, err)
+		}
+		if err != nil && err.Error() != "Service Unavailable" {
+			t.Errorf("%s: got %q, want the status alone", name, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package translate
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const romanizeInstruction = "You are a transliterator.\n" +
+	"Whenever you receive a message written in %s, you will only respond with its romanization.\n" +
+	"Remember: the output should only contain the romanized message."
+
+// IsCJK reports whether lang is Chinese, Japanese or Korean
+func IsCJK(lang string) bool {
+	switch BaseLanguage(lang) {
+	case "zh", "ja", "ko":
+		return true
+	}
+	return false
+}
+
+// BaseLanguage returns the language subtag of a language code, such as zh for zh-TW
+func BaseLanguage(lang string) string {
+	base, _, _ := strings.Cut(strings.ToLower(lang), "-")
+	return base
+}
+
+// Romanize returns the romanization of text, which is written in lang
+func (gt *Client) Romanize(lang, text string) (string, error) {
+	if gt.useLLM() {
+		ctx, cancel := gt.requestContext()
+		defer cancel()
+		return withRetry(ctx, gt.retries, func() (string, error) {
+			return gt.generate(ctx, lang, fmt.Sprintf(romanizeInstruction, LanguageName(lang)), text)
+		})
+	}
+	switch BaseLanguage(lang) {
+	case "ko":
+		return romanizeHangul(text), nil
+	case "ja":
+		return romanizeKana(text), nil
+	}
+	return "", fmt.Errorf("romanization of %s requires an LLM backend", LanguageName(lang))
+}
+
+var (
+	hangulInitials = []string{"g", "kk", "n", "d", "tt", "r", "m", "b", "pp", "s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h"}
+	hangulMedials  = []string{"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o", "wa", "wae", "oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i"}
+	hangulFinals   = []string{"", "k", "k", "k", "n", "n", "n", "t", "l", "k", "m", "l", "l", "l", "p", "l", "m", "p", "p", "t", "t", "ng", "t", "t", "k", "t", "p", "t"}
+)
+
+// romanizeHangul transliterates Hangul syllables with the Revised Romanization of Korean,
+// letter by letter and without applying sound-change rules
+func romanizeHangul(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		if r < 0xAC00 || r > 0xD7A3 {
+			sb.WriteRune(r)
+			continue
+		}
+		idx := int(r - 0xAC00)
+		sb.WriteString(hangulInitials[idx/588])
+		sb.WriteString(hangulMedials[idx%588/28])
+		sb.WriteString(hangulFinals[idx%28])
+	}
+	return sb.String()
+}
+
+var kana = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n", 'ゔ': "vu",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+	'ゃ': "ya", 'ゅ': "yu", 'ょ': "yo", 'ゎ': "wa",
+	'。': ".", '、': ",", '「': "\"", '」': "\"",
+}
+
+// romanizeKana transliterates hiragana and katakana with the Hepburn romanization,
+// leaving kanji and other characters untouched
+func romanizeKana(text string) string {
+	var sb strings.Builder
+	runes := []rune(text)
+	double := false
+	last := ""
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r >= 0x30A1 && r <= 0x30F6 {
+			// katakana share the layout of hiragana
+			r -= 0x60
+		}
+		switch r {
+		case 'っ':
+			double = true
+			continue
+		case 'ー':
+			if last != "" {
+				sb.WriteString(last[len(last)-1:])
+			}
+			continue
+		}
+		rom, ok := kana[r]
+		if !ok {
+			sb.WriteRune(runes[i])
+			last = ""
+			continue
+		}
+		if i+1 < len(runes) && strings.HasSuffix(rom, "i") && len(rom) > 1 {
+			next := runes[i+1]
+			if next >= 0x30A1 && next <= 0x30F6 {
+				next -= 0x60
+			}
+			if next == 'ゃ' || next == 'ゅ' || next == 'ょ' {
+				small := kana[next]
+				rom = strings.TrimSuffix(rom, "i")
+				if strings.HasSuffix(rom, "sh") || strings.HasSuffix(rom, "ch") || rom == "j" {
+					small = small[1:]
+				}
+				rom += small
+				i++
+			}
+		}
+		if double && unicode.IsLetter(rune(rom[0])) && !strings.ContainsRune("aeiou", rune(rom[0])) {
+			if strings.HasPrefix(rom, "ch") {
+				sb.WriteByte('t')
+			} else {
+				sb.WriteByte(rom[0])
+			}
+		}
+		double = false
+		sb.WriteString(rom)
+		last = rom
+	}
+	return sb.String()
+}
@@ -0,0 +1,131 @@
+package translate
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// scriptLanguages maps the scripts to the language using them or, if they are shared by
+// many languages, to the most likely one
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	lang   string
+	shared bool
+}{
+	{unicode.Latin, "en", true},
+	{unicode.Cyrillic, "ru", true},
+	{unicode.Arabic, "ar", true},
+	{unicode.Devanagari, "hi", true},
+	{unicode.Bengali, "bn", true},
+	{unicode.Ethiopic, "am", true},
+	{unicode.Hebrew, "he", false},
+	{unicode.Hangul, "ko", false},
+	{unicode.Hiragana, "ja", false},
+	{unicode.Katakana, "ja", false},
+	{unicode.Thai, "th", false},
+	{unicode.Greek, "el", false},
+	{unicode.Georgian, "ka", false},
+	{unicode.Armenian, "hy", false},
+	{unicode.Khmer, "km", false},
+	{unicode.Lao, "lo", false},
+	{unicode.Myanmar, "my", false},
+	{unicode.Sinhala, "si", false},
+	{unicode.Tamil, "ta", false},
+	{unicode.Telugu, "te", false},
+	{unicode.Kannada, "kn", false},
+	{unicode.Malayalam, "ml", false},
+	{unicode.Gujarati, "gu", false},
+	{unicode.Gurmukhi, "pa", false},
+}
+
+// MinScriptShare is the share of the letters that must belong to a script to tell the language
+const MinScriptShare = 0.8
+
+// ScriptLanguage guesses the language of text from its main script, returning the share of the
+// letters written in it as the confidence, and whether the guess is reliable: the script is used
+// by a single language and makes most of the text
+func ScriptLanguage(text string) (string, float64, bool) {
+	counts := make(map[string]int)
+	shared := make(map[string]bool)
+	letters, han := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Han, r) {
+			han++
+			continue
+		}
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.script, r) {
+				counts[s.lang]++
+				shared[s.lang] = s.shared
+				break
+			}
+		}
+	}
+	// kanji only tell Japanese apart from Chinese when mixed with kana, and hanzi are
+	// shared by the written forms of Chinese
+	if counts["ja"] > 0 {
+		counts["ja"] += han
+	} else if han > 0 {
+		counts["zh"], shared["zh"] = han, true
+	}
+	// ties go to the script listed first, and Chinese comes last
+	best := ""
+	for _, s := range scriptLanguages {
+		if counts[s.lang] > counts[best] {
+			best = s.lang
+		}
+	}
+	if counts["zh"] > counts[best] {
+		best = "zh"
+	}
+	if best == "" {
+		return "", 0, false
+	}
+	share := float64(counts[best]) / float64(letters)
+	return best, share, !shared[best] && share >= MinScriptShare
+}
+
+// ErrNoLetters is returned by the local detection when text has no letters to tell the language from
+var ErrNoLetters = errors.New("the text has no letters to detect the language from")
+
+// languageScripts returns the scripts lang is written in, assuming the Latin alphabet
+// for the languages missing from scriptLanguages
+func languageScripts(lang string) []*unicode.RangeTable {
+	base, _, _ := strings.Cut(lang, "-")
+	switch base {
+	case "zh":
+		return []*unicode.RangeTable{unicode.Han}
+	case "ja":
+		return []*unicode.RangeTable{unicode.Hiragana, unicode.Katakana, unicode.Han}
+	}
+	for _, s := range scriptLanguages {
+		if s.lang == base {
+			return []*unicode.RangeTable{s.script}
+		}
+	}
+	return []*unicode.RangeTable{unicode.Latin}
+}
+
+// ScriptShare returns the share of the letters of text written in the scripts of lang
+func ScriptShare(text, lang string) float64 {
+	scripts := languageScripts(lang)
+	letters, in := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.In(r, scripts...) {
+			in++
+		}
+	}
+	if letters == 0 {
+		return 0
+	}
+	return float64(in) / float64(letters)
+}
@@ -0,0 +1,35 @@
+package translate
+
+import "testing"
+
+func TestScriptLanguage(t *testing.T) {
+	tests := []struct {
+		text     string
+		lang     string
+		share    float64
+		reliable bool
+	}{
+		{"안녕하세요", "ko", 1, true},
+		{"hello", "en", 1, false},
+		{"ab вг", "en", 0.5, false},
+		{"вг ab", "en", 0.5, false},
+		{"שלום עולם", "he", 1, true},
+		{"日本語です", "ja", 1, true},
+		{"中文", "zh", 1, false},
+		{"中文 ab", "en", 0.5, false},
+		{"ab 中文", "en", 0.5, false},
+		{"abc 中文", "en", 0.6, false},
+		{"ab 中文字", "zh", 0.6, false},
+		{"123 !?", "", 0, false},
+	}
+	for _, tt := range tests {
+		// the map used to count the scripts must not decide the ties
+		for range 20 {
+			lang, share, reliable := ScriptLanguage(tt.text)
+			if lang != tt.lang || share != tt.share || reliable != tt.reliable {
+				t.Errorf("ScriptLanguage(%q) = %q, %v, %t, want %q, %v, %t", tt.text, lang, share, reliable, tt.lang, tt.share, tt.reliable)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,574 @@
+// Package translate translates text and detects its language with Google Translate,
+// Gemini, Ollama, OpenAI-compatible APIs, Anthropic or an external command.
+package translate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	cloudtranslate "cloud.google.com/go/translate"
+	translatev3 "cloud.google.com/go/translate/apiv3"
+	"github.com/google/generative-ai-go/genai"
+	"golang.org/x/text/language"
+)
+
+// Translator translates text and detects the language it is written in
+type Translator interface {
+	Translate(targetLang, text string) (string, error)
+	// Detect returns the language of text and the confidence of the detection, from 0 to 1
+	Detect(text string) (string, float64, error)
+}
+
+// Language is a language supported by a backend
+type Language = cloudtranslate.Language
+
+// ErrNoAPIKey is returned when neither the key file nor the environment provide a key
+var ErrNoAPIKey = errors.New("no API key found")
+
+// Client groups the clients and the context needed for translation
+type Client struct {
+	nmtClient *cloudtranslate.Client
+	// v3Client replaces nmtClient with API version 3, for the given project
+	v3Client *translatev3.TranslationClient
+	project  string
+	// llmClient is the Gemini client of the first key, and llms rotates between the keys
+	llmClient *genai.Client
+	llms      *keyRing
+	// ollamaURL is the base URL of the Ollama server, empty when not in use
+	ollamaURL string
+	// execCmd is the command template of the exec backend, run with the source language
+	execCmd string
+	source  string
+	// openaiURL is the base URL of the OpenAI-compatible API, empty when not in use
+	openaiURL string
+	openaiKey string
+	// anthropicKey is the key of the Anthropic API, empty when not in use
+	anthropicKey string
+	// model, temperature and topP configure the backends talking HTTP directly
+	model       string
+	temperature float32
+	topP        float32
+	httpClient  *http.Client
+	// prompt is the system instruction given to the LLM backends
+	prompt string
+	// languages are the known and learned languages, preferred by Detect among close candidates
+	languages []string
+	// nmtModel is the Google Translate model: nmt or base
+	nmtModel string
+	// glossary is applied to the output of the backends that can't be instructed to follow it
+	glossary Glossary
+	// stream receives the Gemini responses incrementally
+	stream bool
+	// trim removes the whitespace and the quotes wrapping the LLM answers
+	trim bool
+	// unescape decodes the HTML entities of the Google Translate v2 translations; without it, v2 is
+	// asked for plain text, as v3 always is, so that the entities of the original text are kept as they are
+	unescape bool
+	// onUsage is called for every billable request, if set
+	onUsage func(UsageEntry)
+	// backend is the name of the backend in use, and geminiModel the model of the Gemini client;
+	// they key the cache along with settings, the hash of the other options changing the output
+	backend     string
+	geminiModel string
+	settings    string
+	cache       *Cache
+	// langsDir is the directory caching the supported languages, disabled when empty,
+	// and refreshLangs ignores the cached ones
+	langsDir     string
+	refreshLangs bool
+	ctx          context.Context
+	// timeout bounds every request made with ctx, no limit when negative
+	timeout time.Duration
+	// retries is the maximum number of attempts for requests failing with transient errors
+	retries int
+	// fallback translates with Google Translate when the LLM fails, and fellBack is set once it did,
+	// shared with the copies made by WithContext
+	fallback bool
+	fellBack *atomic.Bool
+}
+
+// readAPIKey returns the key stored in keyFile if set, or the value of envVar otherwise
+func readAPIKey(envVar, keyFile string) (string, error) {
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRightFunc(string(data), unicode.IsSpace), nil
+	}
+	if key := os.Getenv(envVar); key != "" {
+		return key, nil
+	}
+	return "", fmt.Errorf("%w in %s", ErrNoAPIKey, envVar)
+}
+
+// DefaultTimeout and DefaultRetries are the Timeout and Retries of the Options leaving them zero
+const (
+	DefaultTimeout = 30 * time.Second
+	DefaultRetries = 3
+)
+
+// Options configures the client created by NewClient, whose zero values are the defaults of the command
+type Options struct {
+	// Backend is one of gemini, ollama, openai, anthropic, nmt or exec, nmt when empty
+	Backend string
+	Model   string
+	KeyFile string
+	// Credentials is the service account file used instead of the API key by the nmt backend
+	Credentials string
+	// NMTModel is the Google Translate model: nmt, the default, or base
+	NMTModel string
+	// APIVersion selects the Cloud Translation API, 2 by default or 3, the latter for Project
+	APIVersion int
+	Project    string
+	// Temperature and TopP control the sampling of the LLM backends, TopP is unset when zero;
+	// a zero Temperature is kept, unlike the other zero values
+	Temperature float32
+	TopP        float32
+	// Proxy overrides the proxy configured in the environment
+	Proxy string
+	// BaseURL overrides the endpoint of the OpenAI-compatible backend
+	BaseURL string
+	// Format is the format of the text to translate: "text", the default, or "markdown"
+	Format string
+	// Known and Learn are the languages the default system instruction translates between
+	Known string
+	Learn []string
+	// CustomPrompt replaces the default system instruction when set
+	CustomPrompt string
+	// Domain describes where the text comes from, to guide the tone and terminology of the LLM
+	Domain string
+	// Register is the speech level the LLM is asked to use, formal or informal
+	Register string
+	Glossary Glossary
+	// Fallback names the backend used when the LLM fails: empty or "nmt"
+	Fallback string
+	// Timeout bounds every request, without limit when negative, and Retries is the maximum
+	// number of attempts for requests failing with transient errors
+	Timeout time.Duration
+	Retries int
+	// KeyState is the file remembering which Gemini key to use next, not saved when empty
+	KeyState string
+	// ExecCmd is the command template of the exec backend, and Source the language of the text
+	ExecCmd string
+	Source  string
+	// Stream receives the Gemini responses incrementally
+	Stream bool
+	// NoTrim keeps the whitespace and the quotes wrapping the LLM answers
+	NoTrim bool
+	// KeepEntities requests the Google Translate v2 translations as plain text instead of
+	// decoding their HTML entities; v3 and the LLMs answer in plain text anyway
+	KeepEntities bool
+	// OnUsage is called for every billable request, if set
+	OnUsage func(UsageEntry)
+	// Cache stores the translations, disabled when nil
+	Cache *Cache
+	// LangsDir is the directory caching the supported languages, disabled when empty,
+	// and RefreshLangs ignores the cached ones
+	LangsDir     string
+	RefreshLangs bool
+}
+
+// withDefaults returns opts with the defaults of the command in place of its zero values
+func (opts Options) withDefaults() Options {
+	if opts.Backend == "" {
+		opts.Backend = "nmt"
+	}
+	if opts.NMTModel == "" {
+		opts.NMTModel = "nmt"
+	}
+	if opts.APIVersion == 0 {
+		opts.APIVersion = 2
+	}
+	if opts.Format == "" {
+		opts.Format = "text"
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Retries == 0 {
+		opts.Retries = DefaultRetries
+	}
+	return opts
+}
+
+// NewClient creates the client of opts.Backend, along with the other Google client
+// when its key is in the environment
+func NewClient(opts Options) (*Client, error) {
+	opts = opts.withDefaults()
+	ctx := context.Background()
+	tr, err := httpTransport(opts.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	gt := &Client{
+		prompt:       opts.prompt(),
+		languages:    append([]string{opts.Known}, opts.Learn...),
+		temperature:  opts.Temperature,
+		topP:         opts.TopP,
+		httpClient:   &http.Client{Transport: tr},
+		stream:       opts.Stream,
+		trim:         !opts.NoTrim,
+		unescape:     !opts.KeepEntities,
+		onUsage:      opts.OnUsage,
+		backend:      opts.Backend,
+		settings:     settingsOf(opts),
+		cache:        opts.Cache,
+		langsDir:     opts.LangsDir,
+		refreshLangs: opts.RefreshLangs,
+		ctx:          ctx,
+		timeout:      opts.Timeout,
+		retries:      opts.Retries,
+		fellBack:     new(atomic.Bool),
+	}
+	switch opts.Backend {
+	case "gemini":
+		if err := gt.initGemini(ctx, opts, tr); err != nil {
+			return nil, err
+		}
+	case "ollama":
+		if opts.Model == "" {
+			opts.Model = "llama3"
+		}
+		slog.Info("using model", "model", opts.Model)
+		gt.ollamaURL, gt.model = OllamaHost(), opts.Model
+	case "openai":
+		baseURL := strings.TrimRight(opts.BaseURL, "/")
+		if baseURL == "" {
+			baseURL = DefaultOpenAIURL
+		}
+		key, err := readAPIKey("OPENAI_APIKEY", opts.KeyFile)
+		if err != nil && baseURL == DefaultOpenAIURL {
+			return nil, err
+		}
+		if opts.Model == "" {
+			opts.Model = "gpt-4o-mini"
+		}
+		slog.Info("using model", "model", opts.Model)
+		gt.openaiURL, gt.openaiKey, gt.model = baseURL, key, opts.Model
+	case "anthropic":
+		key, err := readAPIKey("ANTHROPIC_APIKEY", opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Model == "" {
+			opts.Model = "claude-3-5-haiku-latest"
+		}
+		slog.Info("using model", "model", opts.Model)
+		gt.anthropicKey, gt.model = key, opts.Model
+	case "nmt":
+		if err := gt.initNMT(ctx, opts, tr); err != nil {
+			return nil, err
+		}
+	case "exec":
+		if strings.TrimSpace(opts.ExecCmd) == "" {
+			return nil, ErrNoExecCmd
+		}
+		gt.execCmd, gt.source = opts.ExecCmd, opts.Source
+	default:
+		return nil, fmt.Errorf("unknown backend %q", opts.Backend)
+	}
+	// the other Google client is also initialized when its key is in the environment,
+	// for the features that need both
+	other := opts
+	other.KeyFile = ""
+	if gt.useLLM() {
+		gt.fallback = opts.Fallback == "nmt"
+		if err := gt.initNMT(ctx, other, tr); err != nil && (gt.fallback || !errors.Is(err, ErrNoAPIKey)) {
+			slog.Warn("google translate is unavailable", "err", err)
+		}
+	} else if gt.llmClient == nil && os.Getenv("GEMINI_APIKEY") != "" {
+		if err := gt.initGemini(ctx, other, tr); err != nil {
+			slog.Warn("gemini is unavailable", "err", err)
+		}
+	}
+	return gt, nil
+}
+
+// initGemini creates the Gemini clients and models configured by opts, one for each of
+// the API keys separated by commas or newlines
+func (gt *Client) initGemini(ctx context.Context, opts Options, tr http.RoundTripper) error {
+	key, err := readAPIKey("GEMINI_APIKEY", opts.KeyFile)
+	if err != nil {
+		return err
+	}
+	keys := splitKeys(key)
+	if len(keys) == 0 {
+		return fmt.Errorf("%w in GEMINI_APIKEY", ErrNoAPIKey)
+	}
+	model := opts.Model
+	if opts.Backend != "gemini" {
+		// the model belongs to the backend chosen by the user
+		model = "gemini-1.5-flash"
+	} else {
+		if model == "" {
+			model = "gemini-1.5-flash"
+		}
+		slog.Info("using model", "model", model, "keys", len(keys))
+	}
+	ring := newKeyRing(opts.KeyState)
+	for _, key := range keys {
+		client, err := genai.NewClient(ctx, googleOptions(key, tr)...)
+		if err != nil {
+			ring.close()
+			return err
+		}
+		llm := client.GenerativeModel(model)
+		llm.SetTemperature(opts.Temperature)
+		if opts.TopP > 0 {
+			llm.SetTopP(opts.TopP)
+		}
+		llm.SystemInstruction = &genai.Content{
+			Parts: []genai.Part{genai.Text(opts.prompt())},
+		}
+		ring.add(client, llm)
+	}
+	gt.llmClient, gt.llms, gt.geminiModel = ring.clients[0], ring, model
+	return nil
+}
+
+// initNMT creates the Google Translate client of the API version in opts
+func (gt *Client) initNMT(ctx context.Context, opts Options, tr http.RoundTripper) error {
+	if opts.NMTModel != "nmt" && opts.NMTModel != "base" {
+		return fmt.Errorf("unknown nmt model %q, expected nmt or base", opts.NMTModel)
+	}
+	auth, err := translateAuth(ctx, opts, tr)
+	if err != nil {
+		return err
+	}
+	switch opts.APIVersion {
+	case 2:
+		if gt.nmtClient, err = cloudtranslate.NewClient(ctx, auth...); err != nil {
+			return err
+		}
+	case 3:
+		if gt.v3Client, err = newV3Client(ctx, opts.Project, auth); err != nil {
+			return err
+		}
+		gt.project = opts.Project
+	default:
+		return fmt.Errorf("unknown API version %d, expected 2 or 3", opts.APIVersion)
+	}
+	gt.nmtModel, gt.glossary = opts.NMTModel, opts.Glossary
+	return nil
+}
+
+// useLLM reports whether the backend chosen by the user is an LLM, whichever other clients are initialized
+func (gt *Client) useLLM() bool {
+	return gt.backend != "nmt" && gt.backend != "exec"
+}
+
+// hasNMT reports whether a Google Translate client was initialized, as the backend or the fallback
+func (gt *Client) hasNMT() bool {
+	return gt.nmtClient != nil || gt.v3Client != nil
+}
+
+// Close releases the clients
+func (gt *Client) Close() {
+	if gt.nmtClient != nil {
+		gt.nmtClient.Close()
+	}
+	if gt.v3Client != nil {
+		gt.v3Client.Close()
+	}
+	if gt.llms != nil {
+		gt.llms.close()
+	}
+}
+
+// Translate translates text into targetLang
+func (gt *Client) Translate(targetLang, text string) (string, error) {
+	return gt.translateWith(gt.backend, targetLang, text)
+}
+
+// translateWith translates text into targetLang with backend, the chosen one or the other one initialized
+func (gt *Client) translateWith(backend, targetLang, text string) (string, error) {
+	lang, err := language.Parse(targetLang)
+	if err != nil {
+		return "", err
+	}
+	key := cacheKey{Backend: backend, Model: gt.modelOf(backend), Settings: gt.settings, Text: text, Target: lang.String()}
+	if gt.cache != nil {
+		if trans, ok := gt.cache.get(key); ok {
+			slog.Debug("using cached translation")
+			return trans, nil
+		}
+	}
+	ctx, cancel := gt.requestContext()
+	defer cancel()
+	trans, err := withRetry(ctx, gt.retries, func() (string, error) {
+		return gt.request(ctx, backend, lang, text)
+	})
+	if err != nil && backend == gt.backend && gt.fallback && gt.hasNMT() && gt.ctx.Err() == nil {
+		// fallback translations are not cached, so that the LLM is tried again next time
+		return gt.translateFallback(lang, text, err)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+	if gt.cache != nil {
+		gt.cache.put(key, trans)
+		if err := gt.cache.save(); err != nil {
+			slog.Warn("unable to save the cache", "err", err)
+		}
+	}
+	return trans, err
+}
+
+// modelOf returns the model backend translates with, the command for the exec backend
+func (gt *Client) modelOf(backend string) string {
+	switch backend {
+	case "gemini":
+		return gt.geminiModel
+	case "nmt":
+		return gt.nmtModel
+	case "exec":
+		return gt.execCmd
+	}
+	return gt.model
+}
+
+// WithContext returns a copy of the client making its requests with ctx, so that canceling
+// ctx aborts them; the copy shares the clients and the cache of gt
+func (gt *Client) WithContext(ctx context.Context) Translator {
+	return gt.withContext(ctx)
+}
+
+func (gt *Client) withContext(ctx context.Context) *Client {
+	c := *gt
+	c.ctx = ctx
+	return &c
+}
+
+// requestContext returns the context for a single request, bounded by the timeout
+func (gt *Client) requestContext() (context.Context, context.CancelFunc) {
+	if gt.timeout <= 0 {
+		return context.WithCancel(gt.ctx)
+	}
+	return context.WithTimeout(gt.ctx, gt.timeout)
+}
+
+// request sends text to backend for translation into lang
+func (gt *Client) request(ctx context.Context, backend string, lang language.Tag, text string) (string, error) {
+	if backend == "exec" {
+		return gt.requestExec(ctx, lang, text)
+	} else if backend != "nmt" {
+		resp, err := gt.generate(ctx, lang.String(), gt.targetPrompt(lang), text)
+		if err != nil {
+			return "", err
+		}
+		if gt.trim {
+			resp = trimResponse(resp, text)
+		}
+		return resp, nil
+	} else if gt.hasNMT() {
+		return gt.requestNMT(ctx, lang, text)
+	}
+	return "", errors.New("no translation client was initialized")
+}
+
+// requestNMT sends text to Google Translate for translation into lang
+func (gt *Client) requestNMT(ctx context.Context, lang language.Tag, text string) (string, error) {
+	if gt.v3Client != nil {
+		return gt.translateV3(ctx, lang, text)
+	}
+	opts := &cloudtranslate.Options{Model: gt.nmtModel}
+	if !gt.unescape {
+		opts.Format = cloudtranslate.Text
+	}
+	resp, err := gt.nmtClient.Translate(ctx, []string{text}, lang, opts)
+	if err != nil {
+		return "", err
+	}
+	gt.recordUsage("nmt", lang.String(), len([]rune(text)), 0)
+	return gt.glossary.apply(gt.unescapeHTML(resp[0].Text)), nil
+}
+
+// unescapeHTML decodes the HTML entities of trans, unless unescaping is disabled
+func (gt *Client) unescapeHTML(trans string) string {
+	if !gt.unescape {
+		return trans
+	}
+	return html.UnescapeString(trans)
+}
+
+// Detect returns the language code of text and the confidence of the detection,
+// which is always 1 for the backends that don't report it
+func (gt *Client) Detect(text string) (string, float64, error) {
+	if gt.backend == "exec" {
+		return detectScript(text)
+	}
+	if gt.useLLM() {
+		lang, err := gt.llmDetect(text)
+		if err == nil || !gt.fallback || !gt.hasNMT() || gt.ctx.Err() != nil {
+			return lang, 1, err
+		}
+		slog.Warn("the LLM detection failed, falling back to nmt", "err", err)
+		gt.fellBack.Store(true)
+	}
+	ctx, cancel := gt.requestContext()
+	defer cancel()
+	dets, err := withRetry(ctx, gt.retries, func() ([]cloudtranslate.Detection, error) {
+		if gt.v3Client != nil {
+			return gt.detectV3(ctx, text)
+		}
+		resp, err := gt.nmtClient.DetectLanguage(ctx, []string{text})
+		if err != nil {
+			return nil, err
+		}
+		return resp[0], nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", 0, ctx.Err()
+		}
+		return "", 0, err
+	}
+	if len(dets) == 0 {
+		return "", 0, errors.New("empty response from the translation API")
+	}
+	gt.recordUsage("nmt", "", len([]rune(text)), 0)
+	det := gt.pickDetection(dets)
+	return fmt.Sprint(det.Language), det.Confidence, nil
+}
+
+// SupportedLanguages returns the languages supported by the backend, named in targetLang
+func (gt *Client) SupportedLanguages(targetLang string) ([]Language, error) {
+	lang, err := language.Parse(targetLang)
+	if err != nil {
+		return nil, err
+	}
+	if gt.backend != "nmt" {
+		return staticLanguages(lang), nil
+	}
+	if langs, ok := gt.cachedLanguages(lang); ok {
+		return langs, nil
+	}
+	ctx, cancel := gt.requestContext()
+	defer cancel()
+	var langs []Language
+	if gt.nmtClient != nil {
+		langs, err = gt.nmtClient.SupportedLanguages(ctx, lang)
+	} else if gt.v3Client != nil {
+		langs, err = gt.languagesV3(ctx, lang)
+	} else {
+		return nil, errors.New("no translation client was initialized")
+	}
+	if err != nil {
+		return nil, err
+	}
+	gt.cacheLanguages(lang, langs)
+	return langs, nil
+}
@@ -0,0 +1,54 @@
+package translate
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestUnescape(t *testing.T) {
+	respond := func(w http.ResponseWriter, req ollamaRequest) {
+		json.NewEncoder(w).Encode(ollamaResponse{Response: "if a &amp;&amp; b &lt; c { return &quot;ok&quot; }"})
+	}
+	// the answers of the LLMs are plain text, whose entities are meant literally
+	want := "if a &amp;&amp; b &lt; c { return &quot;ok&quot; }"
+	for _, keep := range []bool{false, true} {
+		gt := newOllamaClient(t, Options{Known: "en", Learn: []string{"es"}, KeepEntities: keep}, respond)
+		got, err := gt.Translate("en", "si a && b < c")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Translate keeping the entities %t = %q, want %q", keep, got, want)
+		}
+	}
+}
+
+func TestUnescapeHTML(t *testing.T) {
+	if got := (&Client{unescape: true}).unescapeHTML("Tom &amp; Jerry&#39;s"); got != "Tom & Jerry's" {
+		t.Errorf("unescapeHTML = %q, want the entities decoded", got)
+	}
+	if got := (&Client{}).unescapeHTML("Tom &amp; Jerry"); got != "Tom &amp; Jerry" {
+		t.Errorf("unescapeHTML without unescape = %q, want the text as it is", got)
+	}
+}
+
+func TestNewClientDefaults(t *testing.T) {
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("GOOGLE_TRANSLATE_APIKEY", "key")
+	gt, err := NewClient(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gt.Close()
+	if gt.backend != "nmt" || gt.nmtModel != "nmt" || gt.nmtClient == nil {
+		t.Errorf("NewClient(Options{}) uses the %s backend with the %q model, want nmt v2", gt.backend, gt.nmtModel)
+	}
+	if gt.timeout != DefaultTimeout || gt.retries != DefaultRetries || !gt.trim || !gt.unescape {
+		t.Errorf("NewClient(Options{}) has timeout %v, %d retries, trim %t and unescape %t, want the defaults of the command",
+			gt.timeout, gt.retries, gt.trim, gt.unescape)
+	}
+	if want := settingsOf(Options{Backend: "nmt", NMTModel: "nmt", APIVersion: 2, Format: "text"}); gt.settings != want {
+		t.Errorf("NewClient(Options{}) has the settings %s, want those of the explicit defaults %s", gt.settings, want)
+	}
+}
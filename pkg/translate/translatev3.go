@@ -0,0 +1,86 @@
+package translate
+
+import (
+	"context"
+	"errors"
+
+	cloudtranslate "cloud.google.com/go/translate"
+	translatev3 "cloud.google.com/go/translate/apiv3"
+	"cloud.google.com/go/translate/apiv3/translatepb"
+	"golang.org/x/text/language"
+	"google.golang.org/api/option"
+)
+
+// v3Parent returns the resource the Cloud Translation v3 requests are made for
+func (gt *Client) v3Parent() string {
+	return "projects/" + gt.project + "/locations/global"
+}
+
+// v3Model returns the name of the v3 model matching gt.nmtModel
+func (gt *Client) v3Model() string {
+	return gt.v3Parent() + "/models/general/" + gt.nmtModel
+}
+
+// translateV3 translates text into lang with the Cloud Translation v3 API
+func (gt *Client) translateV3(ctx context.Context, lang language.Tag, text string) (string, error) {
+	resp, err := gt.v3Client.TranslateText(ctx, &translatepb.TranslateTextRequest{
+		Parent:             gt.v3Parent(),
+		Contents:           []string{text},
+		MimeType:           "text/plain",
+		TargetLanguageCode: lang.String(),
+		Model:              gt.v3Model(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.GetTranslations()) == 0 {
+		return "", errors.New("empty response from the translation API")
+	}
+	gt.recordUsage("nmt", lang.String(), len([]rune(text)), 0)
+	// requested as plain text, the translation has no entities of its own to decode
+	return gt.glossary.apply(resp.GetTranslations()[0].GetTranslatedText()), nil
+}
+
+// detectV3 returns the likely languages of text and their confidence with the Cloud Translation v3 API
+func (gt *Client) detectV3(ctx context.Context, text string) ([]cloudtranslate.Detection, error) {
+	resp, err := gt.v3Client.DetectLanguage(ctx, &translatepb.DetectLanguageRequest{
+		Parent:   gt.v3Parent(),
+		Source:   &translatepb.DetectLanguageRequest_Content{Content: text},
+		MimeType: "text/plain",
+	})
+	if err != nil {
+		return nil, err
+	}
+	var dets []cloudtranslate.Detection
+	for _, det := range resp.GetLanguages() {
+		dets = append(dets, cloudtranslate.Detection{
+			Language:   language.Make(det.GetLanguageCode()),
+			Confidence: float64(det.GetConfidence()),
+		})
+	}
+	return dets, nil
+}
+
+// languagesV3 returns the languages supported by the Cloud Translation v3 API, named in lang
+func (gt *Client) languagesV3(ctx context.Context, lang language.Tag) ([]cloudtranslate.Language, error) {
+	resp, err := gt.v3Client.GetSupportedLanguages(ctx, &translatepb.GetSupportedLanguagesRequest{
+		Parent:              gt.v3Parent(),
+		DisplayLanguageCode: lang.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var langs []cloudtranslate.Language
+	for _, l := range resp.GetLanguages() {
+		langs = append(langs, cloudtranslate.Language{Name: l.GetDisplayName(), Tag: language.Make(l.GetLanguageCode())})
+	}
+	return langs, nil
+}
+
+// newV3Client returns a Cloud Translation v3 client, over REST so that it uses the HTTP client in auth
+func newV3Client(ctx context.Context, project string, auth []option.ClientOption) (*translatev3.TranslationClient, error) {
+	if project == "" {
+		return nil, errors.New("the v3 API requires a project, set -project or GOOGLE_CLOUD_PROJECT")
+	}
+	return translatev3.NewTranslationRESTClient(ctx, auth...)
+}
@@ -0,0 +1,39 @@
+package translate
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// quotePairs are the quotes LLMs wrap their answers in
+var quotePairs = [][2]string{
+	{`"`, `"`}, {"'", "'"}, {"`", "`"}, {"“", "”"}, {"‘", "’"}, {"«", "»"}, {"「", "」"}, {"『", "』"},
+}
+
+// trimResponse removes the surrounding whitespace of an LLM answer and, unless the source text
+// itself is wrapped the same way, the code fence or the pair of quotes wrapping all of it
+func trimResponse(resp, source string) string {
+	resp = strings.TrimSpace(resp)
+	source = strings.TrimSpace(source)
+	if strings.HasPrefix(resp, "```") && strings.HasSuffix(resp, "```") && !strings.HasPrefix(source, "```") {
+		inner := strings.TrimSuffix(strings.TrimPrefix(resp, "```"), "```")
+		// the info string of the fence, such as a language name, is dropped with its line
+		if _, body, ok := strings.Cut(inner, "\n"); ok && !strings.Contains(inner, "```") {
+			return strings.TrimSpace(body)
+		}
+	}
+	for _, q := range quotePairs {
+		if wrapped(resp, q) && !wrapped(source, q) {
+			inner := resp[len(q[0]) : len(resp)-len(q[1])]
+			// "a" and "b" is not wrapped in quotes
+			if !strings.Contains(inner, q[0]) && !strings.Contains(inner, q[1]) {
+				return strings.TrimSpace(inner)
+			}
+		}
+	}
+	return resp
+}
+
+func wrapped(s string, q [2]string) bool {
+	return utf8.RuneCountInString(s) >= 2 && strings.HasPrefix(s, q[0]) && strings.HasSuffix(s, q[1])
+}
@@ -0,0 +1,20 @@
+package translate
+
+import "time"
+
+// UsageEntry records a single billable request, as stored in the usage log
+type UsageEntry struct {
+	Time    time.Time `json:"time"`
+	Backend string    `json:"backend"`
+	Chars   int       `json:"chars"`
+	Tokens  int32     `json:"tokens,omitempty"`
+	Target  string    `json:"target,omitempty"`
+}
+
+// recordUsage reports a request made to backend to the OnUsage callback, if set
+func (gt *Client) recordUsage(backend, target string, chars int, tokens int32) {
+	if gt.onUsage == nil {
+		return
+	}
+	gt.onUsage(UsageEntry{Time: time.Now(), Backend: backend, Chars: chars, Tokens: tokens, Target: target})
+}
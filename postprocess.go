@@ -0,0 +1,70 @@
+package main
+
+import (
+	"regexp"
+	"unicode"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// postRule is a cleanup applied to the translations into a language
+type postRule struct {
+	name  string
+	apply func(string) string
+}
+
+// postRules are the rules run on the translations, keyed by language tag: those of the base
+// language, such as zh, run before those of the full tag, such as zh-TW. To add a rule, append
+// a postRule to the list of its tag; the rules of a tag run in order.
+var postRules = map[string][]postRule{
+	"ja": {{"remove the spaces between Japanese characters", removeCJKSpaces}},
+	"zh": {{"remove the spaces between Chinese characters", removeCJKSpaces}},
+	"ko": {{"remove the spaces before punctuation", removeSpaceBeforePunct}},
+}
+
+// postprocess runs the rules of lang on text
+func postprocess(lang, text string) string {
+	base := translate.BaseLanguage(lang)
+	for _, rule := range postRules[base] {
+		text = rule.apply(text)
+	}
+	if lang != base {
+		for _, rule := range postRules[lang] {
+			text = rule.apply(text)
+		}
+	}
+	return text
+}
+
+// isCJKRune reports whether r is a Chinese or Japanese character, or a CJK or fullwidth punctuation mark
+func isCJKRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) ||
+		r >= 0x3000 && r <= 0x303f || r >= 0xff00 && r <= 0xffef
+}
+
+// removeCJKSpaces removes the spaces and tabs between two CJK characters or punctuation marks
+func removeCJKSpaces(text string) string {
+	runes := []rune(text)
+	out := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == ' ' || runes[i] == '\t' {
+			j := i
+			for j < len(runes) && (runes[j] == ' ' || runes[j] == '\t') {
+				j++
+			}
+			if len(out) > 0 && j < len(runes) && isCJKRune(out[len(out)-1]) && isCJKRune(runes[j]) {
+				i = j - 1
+				continue
+			}
+		}
+		out = append(out, runes[i])
+	}
+	return string(out)
+}
+
+var spaceBeforePunct = regexp.MustCompile(`[ \t]+([.,!?;:)])`)
+
+// removeSpaceBeforePunct removes the spaces and tabs before closing punctuation
+func removeSpaceBeforePunct(text string) string {
+	return spaceBeforePunct.ReplaceAllString(text, "$1")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pronouncer is implemented by the translators able to transcribe words in IPA
+type pronouncer interface {
+	Pronounce(lang, word string) (string, error)
+}
+
+// maxIdeographicWord is the longest text without spaces in Chinese or Japanese still taken as a single word
+const maxIdeographicWord = 4
+
+// isSingleWord reports whether text, once trimmed, is a single word
+func isSingleWord(text string) bool {
+	fields := strings.Fields(text)
+	if len(fields) != 1 {
+		return false
+	}
+	word := strings.TrimFunc(fields[0], unicode.IsPunct)
+	if word == "" || strings.ContainsFunc(word, func(r rune) bool { return !unicode.IsLetter(r) && r != '-' && r != '\'' }) {
+		return false
+	}
+	return !strings.ContainsFunc(word, isIdeographic) || utf8.RuneCountInString(word) <= maxIdeographicWord
+}
+
+// pronounce returns the IPA transcription of the word being learned when requested and the
+// text is a single word: the translation when translating from the known language, the text otherwise
+func (a *app) pronounce(detected, text, target, trans string) string {
+	p, ok := a.tr.(pronouncer)
+	if !a.ipa || !ok || !isSingleWord(text) {
+		return ""
+	}
+	lang, word := target, strings.TrimSpace(trans)
+	if target == a.known && detected != "" {
+		lang, word = detected, strings.TrimSpace(text)
+	}
+	ipa, err := p.Pronounce(lang, word)
+	if err != nil {
+		slog.Warn("unable to transcribe the pronunciation", "err", err)
+		return ""
+	}
+	return word + " " + ipa
+}
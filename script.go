@@ -0,0 +1,26 @@
+package main
+
+import (
+	"log/slog"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// detectLanguage returns the language of text and the confidence of the detection, guessing
+// it from the script when it is unambiguous to save a request, or always with -local-detect
+func (a *app) detectLanguage(text string) (string, float64, error) {
+	lang, confidence, ok := translate.ScriptLanguage(text)
+	if ok {
+		slog.Info("detected language from the script", "lang", lang)
+		return lang, confidence, nil
+	}
+	if a.localDetect {
+		if lang == "" {
+			return "", 0, translate.ErrNoLetters
+		}
+		// a shared script only suggests its most common language
+		slog.Info("guessed language from the script", "lang", lang)
+		return lang, confidence / 2, nil
+	}
+	return a.tr.Detect(text)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// languageRun is a part of the text written in a single language
+type languageRun struct {
+	text string
+	// lang is empty until a sentence with letters is found
+	lang string
+}
+
+// languageRuns splits text into runs of consecutive sentences in the same language; the
+// sentences without letters join the run before them, or the one after at the start
+func (a *app) languageRuns(text string) ([]languageRun, error) {
+	var runs []languageRun
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for _, sentence := range splitSentences(line) {
+			lang := ""
+			if strings.ContainsFunc(sentence, unicode.IsLetter) {
+				var err error
+				lang, _, err = a.detectLanguage(strings.TrimSpace(sentence))
+				if err != nil {
+					return nil, err
+				}
+			}
+			n := len(runs)
+			switch {
+			case n > 0 && (lang == "" || runs[n-1].lang == lang):
+				runs[n-1].text += sentence
+			case n > 0 && runs[n-1].lang == "":
+				runs[n-1] = languageRun{runs[n-1].text + sentence, lang}
+			default:
+				runs = append(runs, languageRun{sentence, lang})
+			}
+		}
+	}
+	return runs, nil
+}
+
+// runSegments translates every language run of text into its own target, with the
+// same rules as run for a single language, and reassembles them
+func (a *app) runSegments(original, text string) (string, error) {
+	runs, err := a.languageRuns(text)
+	if err != nil {
+		a.pushError("Error", "Unable to detect the language")
+		return "", withCode(exitAPI, err)
+	}
+	var out strings.Builder
+	var langs, targets []string
+	for _, run := range runs {
+		if run.lang == "" {
+			out.WriteString(run.text)
+			continue
+		}
+		target := a.targets(run.lang)[0]
+		slog.Info("translating segment", "lang", translate.LanguageLabel(run.lang), "target", target, "text", run.text)
+		trans, err := keepSpace(run.text, func(core string) (string, error) {
+			return a.translateOrNotify(target, core)
+		})
+		if err != nil {
+			return "", err
+		}
+		if !a.noPostprocess {
+			trans = postprocess(target, trans)
+		}
+		out.WriteString(trans)
+		langs, targets = append(langs, run.lang), append(targets, target)
+	}
+
+	res := result{
+		Source:           original,
+		Translation:      out.String(),
+		DetectedLanguage: strings.Join(langs, ","),
+		TargetLanguage:   strings.Join(targets, ","),
+		Backend:          a.backend,
+	}
+	if err := a.deliver(res, res.Translation, res.Translation, !a.keep); err != nil {
+		return "", err
+	}
+	a.push(fmt.Sprintf("Translating %d segments", len(langs)), res.Translation)
+	a.record(res)
+	return res.Translation, nil
+}
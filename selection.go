@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/arrufat/clipboard"
+)
+
+// the selections tclip can read from and write to
+const (
+	selClipboard = "clipboard"
+	selPrimary   = "primary"
+)
+
+// resolveSelections returns the selections the text is read from and the translation written to:
+// those of selection, "auto" reading the primary selection where there is one and writing the
+// clipboard, unless read or write override them
+func resolveSelections(selection, read, write string) (string, string, error) {
+	var from, to string
+	switch selection {
+	case "auto":
+		from, to = selClipboard, selClipboard
+		if hasPrimary {
+			from = selPrimary
+		}
+	case selClipboard, selPrimary:
+		from, to = selection, selection
+	default:
+		return "", "", fmt.Errorf("unknown selection %q, expected clipboard, primary or auto", selection)
+	}
+	if read != "" {
+		from = read
+	}
+	if write != "" {
+		to = write
+	}
+	for _, sel := range []string{from, to} {
+		switch sel {
+		case selClipboard:
+		case selPrimary:
+			if !hasPrimary {
+				return "", "", errors.New("the primary selection is not available on this platform")
+			}
+		default:
+			return "", "", fmt.Errorf("unknown selection %q, expected clipboard or primary", sel)
+		}
+	}
+	return from, to, nil
+}
+
+// errNoText is returned by readText when the selection holds nothing worth translating
+var errNoText = errors.New("no text selected")
+
+// readText reads the text from the chosen selection, failing with errNoText if it is blank
+func (a *app) readText() (string, error) {
+	text, err := a.readSelection()
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", errNoText
+	}
+	return text, nil
+}
+
+// errTooLarge is returned for selections longer than -max-chars, unless -force is passed
+var errTooLarge = errors.New("selection too large")
+
+// checkLength fails with errTooLarge, notifying the user, when text is longer than the limit of -max-chars
+func (a *app) checkLength(text string) error {
+	n := utf8.RuneCountInString(text)
+	if a.maxChars <= 0 || n <= a.maxChars || a.force {
+		return nil
+	}
+	a.pushError("Error", fmt.Sprintf("Selection too large (%d chars), pass -force to translate it", n))
+	return fmt.Errorf("%w (%d chars, the limit is %d), pass -force to translate it", errTooLarge, n, a.maxChars)
+}
+
+// readSelection reads the text from the chosen selection
+func (a *app) readSelection() (string, error) {
+	return a.readClipboard(a.readFrom == selPrimary)
+}
+
+// readClipboard reads the clipboard or the primary selection with the tool given by -clipboard-tool,
+// or with the library, falling back to the tool of the platform if there is one
+func (a *app) readClipboard(primary bool) (string, error) {
+	if a.clipboardTool != "" {
+		return clipboardTools[a.clipboardTool].read(primary)
+	}
+	setPrimary(primary)
+	text, err := clipboard.ReadAll()
+	if tool := fallbackTool(a.nativeClipboard); err != nil && tool != "" {
+		slog.Info("unable to read the clipboard, using "+tool, "err", err)
+		return clipboardTools[tool].read(primary)
+	}
+	return text, err
+}
+
+// writeClipboard is the counterpart of readClipboard for writing text
+func (a *app) writeClipboard(text string, primary bool) error {
+	if a.clipboardTool != "" {
+		return clipboardTools[a.clipboardTool].write(text, primary)
+	}
+	setPrimary(primary)
+	err := clipboard.WriteAll(text)
+	if tool := fallbackTool(a.nativeClipboard); err != nil && tool != "" {
+		slog.Info("unable to write the clipboard, using "+tool, "err", err)
+		return clipboardTools[tool].write(text, primary)
+	}
+	return err
+}
+
+// writeSelection writes text to the chosen selection, restoring its previous content if that fails.
+// It must only be called once the translation has succeeded, so that failures leave the selection untouched.
+func (a *app) writeSelection(text string) error {
+	primary := a.writeTo == selPrimary
+	prev, readErr := a.readClipboard(primary)
+	if err := a.writeClipboard(text, primary); err != nil {
+		if readErr == nil {
+			if err := a.writeClipboard(prev, primary); err != nil {
+				slog.Warn("unable to restore the selection", "err", err)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// writeSplit writes trans to the clipboard and source to the primary selection, restoring both
+// selections when either write fails so that they are never left half-updated
+func (a *app) writeSplit(source, trans string) error {
+	prevClipboard, clipboardErr := a.readClipboard(false)
+	prevPrimary, primaryErr := a.readClipboard(true)
+	restore := func(primary bool, prev string, readErr error) {
+		if readErr != nil {
+			return
+		}
+		if err := a.writeClipboard(prev, primary); err != nil {
+			slog.Warn("unable to restore the selection", "primary", primary, "err", err)
+		}
+	}
+	if err := a.writeClipboard(trans, false); err != nil {
+		restore(false, prevClipboard, clipboardErr)
+		return err
+	}
+	if err := a.writeClipboard(source, true); err != nil {
+		restore(true, prevPrimary, primaryErr)
+		restore(false, prevClipboard, clipboardErr)
+		return err
+	}
+	return nil
+}
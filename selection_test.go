@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeClipboard registers a clipboard tool keeping the clipboard in a file, and returns its path
+func fakeClipboard(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clipboard")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	clipboardTools["fake"] = clipboardTool{
+		paste: func(bool) []string { return []string{"cat", path} },
+		copy:  func(bool) []string { return []string{"sh", "-c", `cat > "$0"`, path} },
+	}
+	t.Cleanup(func() { delete(clipboardTools, "fake") })
+	return path
+}
+
+func readClipboardFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func newSelectionApp(tr *fakeTranslator) *app {
+	return &app{
+		tr:            tr,
+		known:         "en",
+		learn:         []string{"es"},
+		backend:       "fake",
+		format:        "text",
+		clipboardTool: "fake",
+		readFrom:      selClipboard,
+		writeTo:       selClipboard,
+		quiet:         true,
+	}
+}
+
+func TestResolveSelections(t *testing.T) {
+	autoRead := selClipboard
+	if hasPrimary {
+		autoRead = selPrimary
+	}
+	tests := []struct {
+		selection, read, write string
+		from, to               string
+		// primary is set when the primary selection is needed, failing without one
+		primary bool
+	}{
+		{"auto", "", "", autoRead, selClipboard, false},
+		{selClipboard, "", "", selClipboard, selClipboard, false},
+		{selPrimary, "", "", selPrimary, selPrimary, true},
+		{selPrimary, "", selClipboard, selPrimary, selClipboard, true},
+		{selClipboard, "", selPrimary, selClipboard, selPrimary, true},
+		{selClipboard, selPrimary, "", selPrimary, selClipboard, true},
+		{"auto", selClipboard, "", selClipboard, selClipboard, false},
+		{selPrimary, selClipboard, selClipboard, selClipboard, selClipboard, false},
+	}
+	for _, tt := range tests {
+		from, to, err := resolveSelections(tt.selection, tt.read, tt.write)
+		if tt.primary && !hasPrimary {
+			if err == nil {
+				t.Errorf("resolveSelections(%q, %q, %q) succeeded without a primary selection", tt.selection, tt.read, tt.write)
+			}
+			continue
+		}
+		if err != nil || from != tt.from || to != tt.to {
+			t.Errorf("resolveSelections(%q, %q, %q) = %q, %q, %v, want %q, %q",
+				tt.selection, tt.read, tt.write, from, to, err, tt.from, tt.to)
+		}
+	}
+	for _, sel := range [][3]string{{"other", "", ""}, {"auto", "other", ""}, {"auto", "", "other"}} {
+		if _, _, err := resolveSelections(sel[0], sel[1], sel[2]); err == nil {
+			t.Errorf("resolveSelections(%q, %q, %q) succeeded, want an error", sel[0], sel[1], sel[2])
+		}
+	}
+}
+
+func TestRunWritesSelection(t *testing.T) {
+	path := fakeClipboard(t, "hola mundo")
+	a := newSelectionApp(&fakeTranslator{})
+	text, err := a.readText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.run(text); err != nil {
+		t.Fatal(err)
+	}
+	if got := readClipboardFile(t, path); got != "HOLA MUNDO" {
+		t.Errorf("clipboard = %q, want the translation", got)
+	}
+}
+
+func TestRunFailureLeavesSelection(t *testing.T) {
+	path := fakeClipboard(t, "hola mundo")
+	errBackend := errors.New("backend down")
+	a := newSelectionApp(&fakeTranslator{err: errBackend})
+	text, err := a.readText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.run(text); !errors.Is(err, errBackend) {
+		t.Errorf("run = %v, want %v", err, errBackend)
+	}
+	if got := readClipboardFile(t, path); got != "hola mundo" {
+		t.Errorf("clipboard = %q, want it untouched", got)
+	}
+}
+
+func TestRunVerifyOnlyShown(t *testing.T) {
+	path := fakeClipboard(t, "hola mundo")
+	a := newSelectionApp(&fakeTranslator{})
+	a.verify = true
+	trans, err := a.run("hola mundo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readClipboardFile(t, path); got != "HOLA MUNDO" || trans != got {
+		t.Errorf("clipboard = %q and run = %q, want only the translation", got, trans)
+	}
+
+	a.concat, a.separator = true, defaultSeparator
+	if _, err := a.run("hola mundo"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readClipboardFile(t, path), "hola mundo"+defaultSeparator+"HOLA MUNDO"; got != want {
+		t.Errorf("appended clipboard = %q, want %q", got, want)
+	}
+
+	var out strings.Builder
+	a.concat, a.out = false, &out
+	if _, err := a.run("hola mundo"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "HOLA MUNDO\nBack-translation: HOLA MUNDO") {
+		t.Errorf("output = %q, want the back-translation", out.String())
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s.]+$`)
+	// pathPattern matches absolute, home-relative and dot-relative Unix paths, and Windows paths
+	pathPattern = regexp.MustCompile(`^(~?/|\.\.?/|[A-Za-z]:\\|\\\\)\S*$`)
+)
+
+// untranslatable describes text if it is a single URL, email address or path, which
+// would only be mangled by a translation, and returns an empty string otherwise
+func untranslatable(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.ContainsAny(text, " \t\n") {
+		return ""
+	}
+	if u, err := url.Parse(text); err == nil && u.Scheme != "" && (u.Host != "" || u.Scheme == "mailto" || u.Scheme == "file") {
+		return "a URL"
+	}
+	if strings.HasPrefix(text, "www.") {
+		return "a URL"
+	}
+	if emailPattern.MatchString(text) {
+		return "an email address"
+	}
+	if pathPattern.MatchString(text) {
+		return "a path"
+	}
+	return ""
+}
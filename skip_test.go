@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestUntranslatable(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"https://example.com/path?q=1", "a URL"},
+		{"  http://localhost:8080\n", "a URL"},
+		{"www.example.com", "a URL"},
+		{"mailto:someone@example.com", "a URL"},
+		{"file:///etc/hosts", "a URL"},
+		{"someone@example.com", "an email address"},
+		{"first.last+tag@mail.example.org", "an email address"},
+		{"/usr/local/bin", "a path"},
+		{"~/notes.txt", "a path"},
+		{"./build.sh", "a path"},
+		{"../README.md", "a path"},
+		{`C:\Users\me`, "a path"},
+		{`\\server\share`, "a path"},
+		{"hola", ""},
+		{"see https://example.com", ""},
+		{"and/or", ""},
+		{"someone@localhost", ""},
+		{"a@b@c.com", ""},
+		{"note: this", ""},
+	}
+	for _, tt := range tests {
+		if got := untranslatable(tt.text); got != tt.want {
+			t.Errorf("untranslatable(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
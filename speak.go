@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// errNoSpeech is returned by speak when no text-to-speech program is installed
+var errNoSpeech = errors.New("no text-to-speech program found, install espeak-ng, espeak or speech-dispatcher")
+
+// speechCommand returns the command that reads text aloud in lang with the TTS program of this platform
+func speechCommand(lang, text string) (*exec.Cmd, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("say", "--", text), nil
+	case "windows":
+		script := "Add-Type -AssemblyName System.Speech; " +
+			"(New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak([Console]::In.ReadToEnd())"
+		cmd := exec.Command("powershell", "-NoProfile", "-Command", script)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd, nil
+	}
+	lang = translate.BaseLanguage(lang)
+	for _, name := range []string{"espeak-ng", "espeak"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return exec.Command(path, "-v", lang, "--", text), nil
+		}
+	}
+	if path, err := exec.LookPath("spd-say"); err == nil {
+		return exec.Command(path, "-w", "-l", lang, "--", text), nil
+	}
+	return nil, errNoSpeech
+}
+
+// speak reads text aloud in lang, waiting until it is done
+func speak(lang, text string) error {
+	cmd, err := speechCommand(lang, text)
+	if err != nil {
+		return err
+	}
+	return cmd.Run()
+}
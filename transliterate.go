@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// errNoRomanizer is returned with -transliterate-only when the translator can't romanize text
+var errNoRomanizer = errors.New("-transliterate-only is not supported by this backend")
+
+// runTransliterate writes the romanization of text instead of its translation, writing the result
+// to the clipboard like run; the text is left as it is unless it is in Chinese, Japanese or Korean
+func (a *app) runTransliterate(original, text string) (string, error) {
+	r, ok := a.tr.(romanizer)
+	if !ok {
+		return "", withCode(exitUsage, errNoRomanizer)
+	}
+	lang := a.source
+	if lang == "" {
+		var err error
+		if lang, _, err = a.detectLanguage(a.detectionSample(text)); err != nil {
+			// the script tells the languages that need a romanization apart
+			slog.Warn("unable to detect the language, guessing it from the script", "err", err)
+			lang, _, _ = translate.ScriptLanguage(text)
+		}
+	}
+	res := result{Source: original, DetectedLanguage: lang, Backend: a.backend}
+	if !translate.IsCJK(lang) {
+		slog.Info("leaving the text unchanged, it is not in Chinese, Japanese or Korean", "lang", translate.LanguageLabel(lang))
+		res.Translation = original
+		if err := a.deliver(res, original, original, false); err != nil {
+			return "", err
+		}
+		a.push("Not transliterated", "The text is not in Chinese, Japanese or Korean: "+original)
+		return original, nil
+	}
+	roman, err := r.Romanize(lang, text)
+	if err != nil {
+		a.pushError("Error", "Unable to transliterate the text")
+		return "", withCode(exitAPI, err)
+	}
+	slog.Info("transliterated text", "text", roman)
+	res.Translation, res.Romanization = roman, roman
+	if err := a.deliver(res, roman, roman, !a.keep); err != nil {
+		return "", err
+	}
+	a.push("Transliterating: "+original, roman)
+	a.record(res)
+	return roman, nil
+}
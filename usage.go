@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// dataPath returns the path of name in the tclip data directory
+func dataPath(name string) (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "tclip", name), nil
+}
+
+// appendJSONLine appends v as a line of JSON to the file at path, creating it if needed
+func appendJSONLine(path string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(v); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// removeFile removes the file at path, if it exists
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// recordUsage returns the callback appending every request to the usage log at path
+func recordUsage(path string) func(translate.UsageEntry) {
+	return func(entry translate.UsageEntry) {
+		if err := appendJSONLine(path, entry); err != nil {
+			slog.Warn("unable to record the usage", "err", err)
+		}
+	}
+}
+
+// printUsage prints the totals of the usage log per backend
+func printUsage(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Println("no usage recorded yet")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	type total struct {
+		requests int
+		chars    int
+		tokens   int64
+	}
+	totals := map[string]*total{}
+	var first time.Time
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e translate.UsageEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if first.IsZero() {
+			first = e.Time
+		}
+		t, ok := totals[e.Backend]
+		if !ok {
+			t = &total{}
+			totals[e.Backend] = t
+		}
+		t.requests++
+		t.chars += e.Chars
+		t.tokens += int64(e.Tokens)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	backends := make([]string, 0, len(totals))
+	for b := range totals {
+		backends = append(backends, b)
+	}
+	sort.Strings(backends)
+	if !first.IsZero() {
+		fmt.Println("usage since", first.Format(time.DateOnly))
+	}
+	fmt.Printf("%-10s %10s %12s %12s\n", "backend", "requests", "characters", "tokens")
+	for _, b := range backends {
+		t := totals[b]
+		fmt.Printf("%-10s %10d %12d %12d\n", b, t.requests, t.chars, t.tokens)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/arrufat/tclip/pkg/translate"
+)
+
+// contextTranslator is implemented by the translators whose requests can be canceled
+type contextTranslator interface {
+	WithContext(ctx context.Context) translate.Translator
+}
+
+// withContext returns a copy of a translating with ctx, so that canceling ctx aborts the
+// requests in flight and keeps the result out of the selection
+func (a *app) withContext(ctx context.Context) *app {
+	req := *a
+	req.ctx = ctx
+	if tr, ok := a.tr.(contextTranslator); ok {
+		req.tr = tr.WithContext(ctx)
+	}
+	if tr, ok := a.compare.(contextTranslator); ok {
+		req.compare = tr.WithContext(ctx)
+	}
+	return &req
+}
+
+// canceled reports whether the request of a was canceled
+func (a *app) canceled() bool {
+	return a.ctx != nil && a.ctx.Err() != nil
+}
+
+// watch polls the selection every interval and translates it whenever it changes and then
+// stays the same for debounce, until the process receives SIGINT or SIGTERM; a new selection
+// cancels the translation of the previous one if it is still in progress
+func (a *app) watch(interval, debounce time.Duration) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// the selection present at startup is not translated
+	lastSeen, _ := a.readSelection()
+	lastWritten := ""
+	// pending is the new selection waiting to be stable, since changed
+	pending := ""
+	var changed time.Time
+	// done receives what the translation in progress wrote, and is nil when there is none
+	var done chan string
+	// cancel cancels the translation in progress
+	var cancel context.CancelFunc
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	slog.Info("watching the selection", "interval", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			if done != nil {
+				<-done
+			}
+			slog.Info("stopped watching the selection")
+			return
+		case trans := <-done:
+			done = nil
+			if trans != "" {
+				lastWritten = trans
+			}
+			continue
+		case <-ticker.C:
+		}
+		// blank selections are skipped silently, unlike in one-shot mode
+		text, err := a.readText()
+		if errors.Is(err, errNoText) {
+			pending = ""
+			continue
+		} else if err != nil {
+			slog.Warn("unable to read the clipboard", "err", err)
+			continue
+		}
+		if text == lastSeen || text == lastWritten {
+			pending = ""
+			continue
+		}
+		if text != pending {
+			pending, changed = text, time.Now()
+		}
+		if time.Since(changed) < debounce {
+			continue
+		}
+		lastSeen, pending = text, ""
+		if err := a.checkLength(text); err != nil {
+			slog.Warn("skipping the selection", "err", err)
+			continue
+		}
+		if done != nil {
+			slog.Info("canceling the translation of the previous selection")
+			cancel()
+			// it may have been written before it noticed
+			if trans := <-done; trans != "" {
+				lastWritten = trans
+			}
+		}
+		reqCtx, reqCancel := context.WithCancel(ctx)
+		cancel, done = reqCancel, make(chan string, 1)
+		go func(req *app) {
+			defer reqCancel()
+			trans, err := req.run(text)
+			if errors.Is(err, context.Canceled) {
+				slog.Info("canceled the translation of the selection")
+			} else if err != nil {
+				slog.Error("unable to translate the selection", "err", err)
+			}
+			done <- trans
+		}(a.withContext(reqCtx))
+	}
+}
@@ -0,0 +1,446 @@
+//go:build freebsd || linux || netbsd || openbsd || solaris || dragonfly
+
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// the opcodes of the X11 requests needed to own a selection
+const (
+	xCreateWindow      = 1
+	xInternAtom        = 16
+	xChangeProperty    = 18
+	xSetSelectionOwner = 22
+	xGetSelectionOwner = 23
+	xSendEvent         = 25
+)
+
+// the first byte of the messages of the X server: errors, replies and the events handled here
+const (
+	xError            = 0
+	xReply            = 1
+	xPropertyNotify   = 28
+	xSelectionClear   = 29
+	xSelectionRequest = 30
+	xSelectionNotify  = 31
+)
+
+// xOrder is the byte order tclip asks the X server to use
+var xOrder = binary.LittleEndian
+
+var errShortSetup = errors.New("short setup reply from the X server")
+
+// xConn is a minimal X11 client, enough to own a selection: requests are sent one at a
+// time, and the replies are awaited right after the request
+type xConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	// idBase and idMask give the resource ids available to the client
+	idBase, idMask uint32
+	root           uint32
+	// maxRequest is the maximum length of a request, in bytes
+	maxRequest int
+	atoms      map[string]uint32
+	// pending are the events received while waiting for a reply
+	pending [][]byte
+}
+
+// xAddress returns the network and the address of the X server of display, such as ":0" or
+// "localhost:10.0", and the number of the display
+func xAddress(display string) (network, address, number string, err error) {
+	i := strings.LastIndex(display, ":")
+	if i < 0 {
+		return "", "", "", fmt.Errorf("invalid display %q", display)
+	}
+	host := display[:i]
+	number, _, _ = strings.Cut(display[i+1:], ".")
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid display %q", display)
+	}
+	if host == "" || host == "unix" {
+		return "unix", "/tmp/.X11-unix/X" + number, number, nil
+	}
+	return "tcp", net.JoinHostPort(host, strconv.Itoa(6000+n)), number, nil
+}
+
+// xCookie returns the MIT-MAGIC-COOKIE-1 of the display number in the Xauthority file, or
+// nil if there is none, in which case the server may still accept the connection
+func xCookie(number string) []byte {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, ".Xauthority")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	host, _ := os.Hostname()
+	return parseXauthority(data, number, host)
+}
+
+// xauthWild is the family of the Xauthority entries valid for any host
+const xauthWild = 0xffff
+
+// parseXauthority returns the cookie of the display number in the entries of an Xauthority
+// file, preferring the ones of host
+func parseXauthority(data []byte, number, host string) []byte {
+	var found []byte
+	for len(data) >= 2 {
+		family := binary.BigEndian.Uint16(data)
+		data = data[2:]
+		// the address, the display number, the name of the authorization and its data
+		var fields [4][]byte
+		for i := range fields {
+			if len(data) < 2 {
+				return found
+			}
+			n := int(binary.BigEndian.Uint16(data))
+			if len(data) < 2+n {
+				return found
+			}
+			fields[i], data = data[2:2+n], data[2+n:]
+		}
+		addr, num, name, cookie := fields[0], fields[1], fields[2], fields[3]
+		if string(name) != "MIT-MAGIC-COOKIE-1" || len(num) > 0 && string(num) != number {
+			continue
+		}
+		if family == xauthWild || string(addr) == host {
+			return cookie
+		}
+		if found == nil {
+			found = cookie
+		}
+	}
+	return found
+}
+
+// dialX connects to the X server of display
+func dialX(display string) (*xConn, error) {
+	network, address, number, err := xAddress(display)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.Dial(network, address)
+	if err != nil && network == "unix" {
+		// the abstract socket of Linux is there even when the file is not visible
+		if abstract, aerr := net.Dial(network, "@"+address); aerr == nil {
+			conn, err = abstract, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	c, err := newXConn(conn, xCookie(number))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// newXConn sets up the X11 connection conn, authenticating with cookie if it is not nil
+func newXConn(conn net.Conn, cookie []byte) (*xConn, error) {
+	name := ""
+	if cookie != nil {
+		name = "MIT-MAGIC-COOKIE-1"
+	}
+	req := []byte{'l', 0}
+	req = xOrder.AppendUint16(req, 11)
+	req = xOrder.AppendUint16(req, 0)
+	req = xOrder.AppendUint16(req, uint16(len(name)))
+	req = xOrder.AppendUint16(req, uint16(len(cookie)))
+	req = append(req, 0, 0)
+	req = append(req, xPad([]byte(name))...)
+	req = append(req, xPad(cookie)...)
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	c := &xConn{conn: conn, r: bufio.NewReader(conn), atoms: make(map[string]uint32)}
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(c.r, head); err != nil {
+		return nil, err
+	}
+	data := make([]byte, 4*int(xOrder.Uint16(head[6:])))
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return nil, err
+	}
+	switch head[0] {
+	case 0:
+		reason := data[:min(int(head[1]), len(data))]
+		return nil, fmt.Errorf("the X server refused the connection: %s", reason)
+	case 1:
+	default:
+		return nil, errors.New("the X server asked for an unsupported authentication")
+	}
+	if len(data) < 32 {
+		return nil, errShortSetup
+	}
+	c.idBase, c.idMask = xOrder.Uint32(data[4:]), xOrder.Uint32(data[8:])
+	c.maxRequest = 4 * int(xOrder.Uint16(data[18:]))
+	// the vendor and the pixmap formats come before the screens, the root window first
+	screen := 32 + xPadLen(int(xOrder.Uint16(data[16:]))) + 8*int(data[21])
+	if data[20] == 0 || len(data) < screen+4 {
+		return nil, errShortSetup
+	}
+	c.root = xOrder.Uint32(data[screen:])
+	return c, nil
+}
+
+func (c *xConn) Close() error {
+	return c.conn.Close()
+}
+
+func xPadLen(n int) int {
+	return (n + 3) &^ 3
+}
+
+// xPad returns a copy of b padded with zeros to a multiple of 4 bytes
+func xPad(b []byte) []byte {
+	return append(append([]byte(nil), b...), make([]byte, xPadLen(len(b))-len(b))...)
+}
+
+// xUint32s encodes values in the byte order of the connection
+func xUint32s(values ...uint32) []byte {
+	b := make([]byte, 0, 4*len(values))
+	for _, v := range values {
+		b = xOrder.AppendUint32(b, v)
+	}
+	return b
+}
+
+// send writes the request of opcode, with the data byte of its header, followed by body
+func (c *xConn) send(opcode, data byte, body []byte) error {
+	body = xPad(body)
+	req := xOrder.AppendUint16([]byte{opcode, data}, uint16(1+len(body)/4))
+	_, err := c.conn.Write(append(req, body...))
+	return err
+}
+
+// read returns the next error, reply or event sent by the server, starting with the events kept by reply
+func (c *xConn) read() ([]byte, error) {
+	if len(c.pending) > 0 {
+		msg := c.pending[0]
+		c.pending = c.pending[1:]
+		return msg, nil
+	}
+	return c.next()
+}
+
+// next reads the next message from the server
+func (c *xConn) next() ([]byte, error) {
+	msg := make([]byte, 32)
+	if _, err := io.ReadFull(c.r, msg); err != nil {
+		return nil, err
+	}
+	if msg[0] == xReply {
+		extra := make([]byte, 4*int(xOrder.Uint32(msg[4:])))
+		if _, err := io.ReadFull(c.r, extra); err != nil {
+			return nil, err
+		}
+		msg = append(msg, extra...)
+	}
+	return msg, nil
+}
+
+// reply returns the reply of the last request, keeping the events received before it for read
+func (c *xConn) reply() ([]byte, error) {
+	for {
+		msg, err := c.next()
+		if err != nil {
+			return nil, err
+		}
+		switch msg[0] {
+		case xError:
+			return nil, fmt.Errorf("X error %d", msg[1])
+		case xReply:
+			return msg, nil
+		}
+		c.pending = append(c.pending, msg)
+	}
+}
+
+// intern returns the atom named name
+func (c *xConn) intern(name string) (uint32, error) {
+	if atom, ok := c.atoms[name]; ok {
+		return atom, nil
+	}
+	body := append(xOrder.AppendUint16(nil, uint16(len(name))), 0, 0)
+	if err := c.send(xInternAtom, 0, append(body, name...)); err != nil {
+		return 0, err
+	}
+	msg, err := c.reply()
+	if err != nil {
+		return 0, err
+	}
+	atom := xOrder.Uint32(msg[8:])
+	c.atoms[name] = atom
+	return atom, nil
+}
+
+// changeProperty replaces the property of window, or appends to it with appendData, with data
+// of typ in units of format bits
+func (c *xConn) changeProperty(window, property, typ uint32, format byte, appendData bool, data []byte) error {
+	mode := byte(0)
+	if appendData {
+		mode = 2
+	}
+	body := append(xUint32s(window, property, typ), format, 0, 0, 0)
+	body = append(xOrder.AppendUint32(body, uint32(len(data)/int(format/8))), data...)
+	return c.send(xChangeProperty, mode, body)
+}
+
+// xOwner is the X11 selection owner serving text and its HTML version
+type xOwner struct {
+	*xConn
+	window, selection uint32
+	// time is when the window became the owner
+	time uint32
+	// targets are the data offered for each target, with their type
+	targets map[uint32]xTarget
+}
+
+type xTarget struct {
+	typ    uint32
+	format byte
+	data   []byte
+}
+
+// ownSelection makes a new X11 client the owner of selection, PRIMARY or CLIPBOARD, offering
+// content as text and HTML, calls ready once it is, and answers the requests for the selection
+// until another client takes it
+func ownSelection(selection string, content richContent, ready func()) error {
+	display := os.Getenv("DISPLAY")
+	if display == "" {
+		return errors.New("no X display, the text/html target needs X11 or XWayland")
+	}
+	c, err := dialX(display)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	return c.own(selection, content, ready)
+}
+
+// own is ownSelection on the connection c
+func (c *xConn) own(selection string, content richContent, ready func()) error {
+	atoms := make(map[string]uint32)
+	for _, name := range []string{selection, "TARGETS", "TIMESTAMP", "ATOM", "INTEGER", "STRING", "UTF8_STRING",
+		"TEXT", "text/plain", "text/plain;charset=utf-8", "text/html", "TCLIP_TIME"} {
+		atom, err := c.intern(name)
+		if err != nil {
+			return err
+		}
+		atoms[name] = atom
+	}
+	o := &xOwner{xConn: c, window: c.idBase | 1&c.idMask, selection: atoms[selection], targets: make(map[uint32]xTarget)}
+	text := []byte(content.Text)
+	for _, name := range []string{"UTF8_STRING", "text/plain", "text/plain;charset=utf-8"} {
+		o.targets[atoms[name]] = xTarget{atoms[name], 8, text}
+	}
+	o.targets[atoms["TEXT"]] = xTarget{atoms["UTF8_STRING"], 8, text}
+	o.targets[atoms["text/html"]] = xTarget{atoms["text/html"], 8, []byte(content.HTML)}
+	// the larger selections would need the INCR protocol, left out
+	if n := max(len(text), len(content.HTML)); 24+n > c.maxRequest {
+		return fmt.Errorf("the selection of %d bytes doesn't fit in a request to the X server, limited to %d", n, c.maxRequest)
+	}
+	var offered []uint32
+	for _, name := range []string{"TARGETS", "TIMESTAMP", "UTF8_STRING", "TEXT", "text/plain", "text/plain;charset=utf-8", "text/html"} {
+		offered = append(offered, atoms[name])
+	}
+	o.targets[atoms["TARGETS"]] = xTarget{atoms["ATOM"], 32, xUint32s(offered...)}
+
+	// an input-only window, told about the changes of its properties
+	body := xUint32s(o.window, c.root)
+	body = append(body, 0, 0, 0, 0, 1, 0, 1, 0, 0, 0, 2, 0)
+	if err := c.send(xCreateWindow, 0, append(body, xUint32s(0, 0x800, 0x400000)...)); err != nil {
+		return err
+	}
+	// the owner must give the time of an event rather than the current time, and appending
+	// nothing to a property makes the server send one
+	if err := c.changeProperty(o.window, atoms["TCLIP_TIME"], atoms["STRING"], 8, true, nil); err != nil {
+		return err
+	}
+	for o.time == 0 {
+		msg, err := c.read()
+		if err != nil {
+			return err
+		}
+		if msg[0]&0x7f == xPropertyNotify && xOrder.Uint32(msg[4:]) == o.window {
+			o.time = xOrder.Uint32(msg[12:])
+		}
+	}
+	o.targets[atoms["TIMESTAMP"]] = xTarget{atoms["INTEGER"], 32, xUint32s(o.time)}
+
+	if err := c.send(xSetSelectionOwner, 0, xUint32s(o.window, o.selection, o.time)); err != nil {
+		return err
+	}
+	if err := c.send(xGetSelectionOwner, 0, xUint32s(o.selection)); err != nil {
+		return err
+	}
+	msg, err := c.reply()
+	if err != nil {
+		return err
+	}
+	if xOrder.Uint32(msg[8:]) != o.window {
+		return errors.New("unable to own the selection")
+	}
+	ready()
+	return o.serve()
+}
+
+// serve answers the requests for the selection until another client owns it
+func (o *xOwner) serve() error {
+	for {
+		msg, err := o.read()
+		if err != nil {
+			return err
+		}
+		switch msg[0] & 0x7f {
+		case xSelectionClear:
+			if xOrder.Uint32(msg[12:]) == o.selection {
+				return nil
+			}
+		case xSelectionRequest:
+			time := xOrder.Uint32(msg[4:])
+			requestor, selection := xOrder.Uint32(msg[12:]), xOrder.Uint32(msg[16:])
+			target, property := xOrder.Uint32(msg[20:]), xOrder.Uint32(msg[24:])
+			if err := o.answer(time, requestor, selection, target, property); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// answer stores the data of target in the property of requestor, and notifies it; the targets which
+// are not offered are refused
+func (o *xOwner) answer(time, requestor, selection, target, property uint32) error {
+	if property == 0 {
+		// the clients predating ICCCM leave the property to the owner
+		property = target
+	}
+	t, ok := o.targets[target]
+	if !ok || selection != o.selection {
+		property = 0
+	} else if err := o.changeProperty(requestor, property, t.typ, t.format, false, t.data); err != nil {
+		return err
+	}
+	event := append([]byte{xSelectionNotify, 0, 0, 0}, xUint32s(time, requestor, selection, target, property)...)
+	event = append(event, make([]byte, 32-len(event))...)
+	return o.send(xSendEvent, 0, append(xUint32s(requestor, 0), event...))
+}
@@ -0,0 +1,324 @@
+//go:build freebsd || linux || netbsd || openbsd || solaris || dragonfly
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestXAddress(t *testing.T) {
+	tests := []struct {
+		display, network, address, number string
+	}{
+		{":0", "unix", "/tmp/.X11-unix/X0", "0"},
+		{":1.0", "unix", "/tmp/.X11-unix/X1", "1"},
+		{"unix:2", "unix", "/tmp/.X11-unix/X2", "2"},
+		{"localhost:10.0", "tcp", "localhost:6010", "10"},
+	}
+	for _, tt := range tests {
+		network, address, number, err := xAddress(tt.display)
+		if err != nil || network != tt.network || address != tt.address || number != tt.number {
+			t.Errorf("xAddress(%q) = %q, %q, %q, %v, want %q, %q, %q", tt.display, network, address, number, err, tt.network, tt.address, tt.number)
+		}
+	}
+	for _, display := range []string{"", "0", ":x"} {
+		if _, _, _, err := xAddress(display); err == nil {
+			t.Errorf("xAddress(%q) succeeded, want an error", display)
+		}
+	}
+}
+
+// xauthEntry encodes an entry of an Xauthority file
+func xauthEntry(family uint16, addr, number, name, data string) []byte {
+	b := binary.BigEndian.AppendUint16(nil, family)
+	for _, field := range []string{addr, number, name, data} {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(field)))
+		b = append(b, field...)
+	}
+	return b
+}
+
+func TestParseXauthority(t *testing.T) {
+	var data []byte
+	data = append(data, xauthEntry(256, "other", "0", "MIT-MAGIC-COOKIE-1", "other0")...)
+	data = append(data, xauthEntry(256, "host", "1", "MIT-MAGIC-COOKIE-1", "host1")...)
+	data = append(data, xauthEntry(256, "host", "0", "XDM-AUTHORIZATION-1", "xdm")...)
+	data = append(data, xauthEntry(256, "host", "0", "MIT-MAGIC-COOKIE-1", "host0")...)
+	tests := []struct {
+		data         []byte
+		number, host string
+		want         string
+	}{
+		{data, "0", "host", "host0"},
+		{data, "1", "host", "host1"},
+		{data, "0", "elsewhere", "other0"},
+		{data, "2", "host", ""},
+		{xauthEntry(0xffff, "", "", "MIT-MAGIC-COOKIE-1", "wild"), "3", "host", "wild"},
+		{data[:len(data)-3], "0", "host", "other0"},
+	}
+	for _, tt := range tests {
+		if got := parseXauthority(tt.data, tt.number, tt.host); string(got) != tt.want {
+			t.Errorf("parseXauthority(%q, %q) = %q, want %q", tt.number, tt.host, got, tt.want)
+		}
+	}
+}
+
+// fakeX is an X server handling the requests of the selection owner
+type fakeX struct {
+	t    *testing.T
+	conn net.Conn
+	// mu serializes the writes to conn and guards the fields below
+	mu     sync.Mutex
+	cookie []byte
+	atoms  map[string]uint32
+	owner  uint32
+	// props are the properties set on the other windows, by window and property
+	props map[[2]uint32]fakeProp
+	// notify receives the events sent by the client
+	notify chan []byte
+}
+
+type fakeProp struct {
+	typ    uint32
+	format byte
+	data   []byte
+}
+
+const (
+	fakeRoot      = 0x100
+	fakeTime      = 4242
+	fakeRequestor = 0x500
+)
+
+func (x *fakeX) atom(name string) uint32 {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if atom, ok := x.atoms[name]; ok {
+		return atom
+	}
+	x.atoms[name] = uint32(100 + len(x.atoms))
+	return x.atoms[name]
+}
+
+func (x *fakeX) atomName(atom uint32) string {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for name, a := range x.atoms {
+		if a == atom {
+			return name
+		}
+	}
+	return ""
+}
+
+func (x *fakeX) write(msg []byte) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, err := x.conn.Write(msg); err != nil {
+		x.t.Errorf("writing to the client: %v", err)
+	}
+}
+
+func (x *fakeX) reply(value uint32) {
+	msg := make([]byte, 32)
+	msg[0] = xReply
+	xOrder.PutUint32(msg[8:], value)
+	x.write(msg)
+}
+
+func (x *fakeX) event(code byte, values ...uint32) {
+	msg := append([]byte{code, 0, 0, 0}, xUint32s(values...)...)
+	x.write(append(msg, make([]byte, 32-len(msg))...))
+}
+
+func (x *fakeX) run() {
+	head := make([]byte, 12)
+	if _, err := io.ReadFull(x.conn, head); err != nil {
+		return
+	}
+	auth := make([]byte, xPadLen(int(xOrder.Uint16(head[6:])))+xPadLen(int(xOrder.Uint16(head[8:]))))
+	io.ReadFull(x.conn, auth)
+	x.cookie = auth[xPadLen(int(xOrder.Uint16(head[6:]))):][:xOrder.Uint16(head[8:])]
+
+	// a single screen after a vendor of 4 bytes and no pixmap format
+	data := xUint32s(0, 0x200000, 0x1fffff, 0)
+	data = xOrder.AppendUint16(data, 4)
+	data = xOrder.AppendUint16(data, 0xffff)
+	data = append(data, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	data = append(data, "fake"...)
+	data = append(data, xUint32s(fakeRoot)...)
+	setup := xOrder.AppendUint16([]byte{1, 0, 11, 0, 0, 0}, uint16(len(data)/4))
+	x.write(append(setup, data...))
+
+	var window uint32
+	for {
+		head := make([]byte, 4)
+		if _, err := io.ReadFull(x.conn, head); err != nil {
+			return
+		}
+		body := make([]byte, 4*int(xOrder.Uint16(head[2:]))-4)
+		if _, err := io.ReadFull(x.conn, body); err != nil {
+			return
+		}
+		switch head[0] {
+		case xInternAtom:
+			name := string(body[4:][:xOrder.Uint16(body)])
+			x.reply(x.atom(name))
+		case xCreateWindow:
+			window = xOrder.Uint32(body)
+			if parent := xOrder.Uint32(body[4:]); parent != fakeRoot {
+				x.t.Errorf("the window was created in %#x, want the root", parent)
+			}
+		case xChangeProperty:
+			w, property := xOrder.Uint32(body), xOrder.Uint32(body[4:])
+			if w == window {
+				x.event(xPropertyNotify, window, property, fakeTime)
+				continue
+			}
+			format := body[12]
+			n := int(xOrder.Uint32(body[16:])) * int(format/8)
+			x.mu.Lock()
+			x.props[[2]uint32{w, property}] = fakeProp{xOrder.Uint32(body[8:]), format, body[20:][:n]}
+			x.mu.Unlock()
+		case xSetSelectionOwner:
+			if time := xOrder.Uint32(body[8:]); time != fakeTime {
+				x.t.Errorf("the selection was owned at %d, want the time of the event %d", time, fakeTime)
+			}
+			x.mu.Lock()
+			x.owner = xOrder.Uint32(body)
+			x.mu.Unlock()
+		case xGetSelectionOwner:
+			x.mu.Lock()
+			owner := x.owner
+			x.mu.Unlock()
+			x.reply(owner)
+		case xSendEvent:
+			x.notify <- body[8:40]
+		}
+	}
+}
+
+// convert asks for the selection as target into property, and returns the property set by the
+// owner, which is zero when it refused
+func (x *fakeX) convert(selection, target, property string) (uint32, fakeProp) {
+	x.event(xSelectionRequest, fakeTime+1, x.owner, fakeRequestor, x.atom(selection), x.atom(target), x.atom(property))
+	ev := <-x.notify
+	got := xOrder.Uint32(ev[20:])
+	if ev[0] != xSelectionNotify || xOrder.Uint32(ev[8:]) != fakeRequestor || xOrder.Uint32(ev[16:]) != x.atom(target) {
+		x.t.Errorf("unexpected notification %v", ev)
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return got, x.props[[2]uint32{fakeRequestor, got}]
+}
+
+func TestOwnSelection(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	x := &fakeX{t: t, conn: server, atoms: make(map[string]uint32), props: make(map[[2]uint32]fakeProp), notify: make(chan []byte)}
+	go x.run()
+
+	c, err := newXConn(client, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(x.cookie) != "secret" {
+		t.Errorf("the server got the cookie %q", x.cookie)
+	}
+	content := richContent{Text: "hello world", HTML: htmlTarget(result{Source: "hola mundo", Translation: "hello world", DetectedLanguage: "es", TargetLanguage: "en"})}
+	ready := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- c.own("CLIPBOARD", content, func() { close(ready) }) }()
+	<-ready
+
+	prop, got := x.convert("CLIPBOARD", "TARGETS", "P1")
+	if prop != x.atom("P1") || got.typ != x.atom("ATOM") || got.format != 32 {
+		t.Fatalf("TARGETS gave %#x %+v", prop, got)
+	}
+	var targets []string
+	for i := 0; i < len(got.data); i += 4 {
+		targets = append(targets, x.atomName(xOrder.Uint32(got.data[i:])))
+	}
+	for _, want := range []string{"TARGETS", "UTF8_STRING", "text/plain;charset=utf-8", "text/html"} {
+		if !slices.Contains(targets, want) {
+			t.Errorf("the targets %q miss %s", targets, want)
+		}
+	}
+
+	tests := []struct {
+		target, typ, want string
+	}{
+		{"UTF8_STRING", "UTF8_STRING", "hello world"},
+		{"TEXT", "UTF8_STRING", "hello world"},
+		{"text/plain;charset=utf-8", "text/plain;charset=utf-8", "hello world"},
+		{"text/html", "text/html", `<meta charset="utf-8"><blockquote lang="es">hola mundo</blockquote><p lang="en">hello world</p>`},
+	}
+	for _, tt := range tests {
+		prop, got := x.convert("CLIPBOARD", tt.target, "P2")
+		if prop != x.atom("P2") || got.typ != x.atom(tt.typ) || got.format != 8 || !bytes.Equal(got.data, []byte(tt.want)) {
+			t.Errorf("%s gave %#x %q of type %q, want %q of type %q", tt.target, prop, got.data, x.atomName(got.typ), tt.want, tt.typ)
+		}
+	}
+	if prop, _ := x.convert("CLIPBOARD", "image/png", "P3"); prop != 0 {
+		t.Errorf("image/png was not refused")
+	}
+	if prop, _ := x.convert("PRIMARY", "UTF8_STRING", "P4"); prop != 0 {
+		t.Errorf("the primary selection was not refused")
+	}
+
+	x.event(xSelectionClear, fakeTime+2, x.owner, x.atom("CLIPBOARD"))
+	if err := <-done; err != nil {
+		t.Errorf("own = %v after losing the selection, want nil", err)
+	}
+}
+
+func TestOwnSelectionTooLarge(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	x := &fakeX{t: t, conn: server, atoms: make(map[string]uint32), props: make(map[[2]uint32]fakeProp), notify: make(chan []byte)}
+	go x.run()
+
+	c, err := newXConn(client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := richContent{Text: "hello", HTML: strings.Repeat("x", c.maxRequest)}
+	err = c.own("CLIPBOARD", content, func() { t.Error("a selection too large for a request was owned") })
+	if err == nil || !strings.Contains(err.Error(), "doesn't fit") {
+		t.Errorf("own = %v, want the error of the size", err)
+	}
+}
+
+func TestServeSelectionUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"other"}, {selClipboard, selPrimary}} {
+		if err := serveSelection(args); err == nil || !strings.Contains(err.Error(), "usage") {
+			t.Errorf("serveSelection(%q) = %v, want the usage", args, err)
+		}
+	}
+}
+
+func TestHTMLTargetWithoutDisplay(t *testing.T) {
+	t.Setenv("DISPLAY", "")
+	a := newSelectionApp(&fakeTranslator{})
+	a.writeTo = selClipboard
+	if err := a.writeHTMLSelection("hello", "<p>hello</p>"); err == nil || !strings.Contains(err.Error(), "no X display") {
+		t.Errorf("writeHTMLSelection = %v, want the error of the server", err)
+	}
+
+	// the text is still written the usual way
+	path := fakeClipboard(t, "hola")
+	a.htmlTarget = true
+	if _, err := a.run("hola"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readClipboardFile(t, path); got != "HOLA" {
+		t.Errorf("clipboard = %q, want the translation", got)
+	}
+}